@@ -1,9 +1,13 @@
 package auth
 
 import (
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/taskdb"
 )
 
 // Middleware для проверки авторизации
@@ -27,3 +31,65 @@ func Middleware(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r)
 	}
 }
+
+// FeedMiddleware пускает к машинной ленте по секретному токену в параметре token
+// или в пароле Basic-авторизации, ведь календари и читалки не умеют отправлять нашу куку.
+// В Basic-авторизации подходит и пароль приложения. Без токена действует обычная авторизация.
+func FeedMiddleware(feed string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		given := r.URL.Query().Get("token")
+		if _, pass, ok := r.BasicAuth(); ok && given == "" {
+			// Пароль приложения не принимаем в адресе, где он осядет в журналах и истории
+			appPass := config.AppConfig.Password
+			if appPass != "" && subtle.ConstantTimeCompare([]byte(pass), []byte(appPass)) == 1 {
+				next(w, r)
+				return
+			}
+			given = pass
+		}
+		if given == "" {
+			if config.AppConfig.Password != "" {
+				// Клиенты CalDAV спрашивают пароль у пользователя только после такого вызова
+				w.Header().Set("WWW-Authenticate", `Basic realm="Scheduler"`)
+			}
+			Middleware(next)(w, r)
+			return
+		}
+
+		token, err := taskdb.FeedToken(feed)
+		if err != nil && !errors.Is(err, taskdb.ErrFeedDisabled) {
+			http.Error(w, `{"error": "Failed to check feed token"}`, http.StatusInternalServerError)
+			return
+		}
+		if err != nil || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Scheduler"`)
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error": "Invalid feed token"}`, http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// AdminMiddleware пускает только запросы с заголовком Authorization: Bearer <TODO_ADMIN_TOKEN>
+func AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		token := config.AppConfig.AdminToken
+		if token == "" {
+			http.Error(w, `{"error": "Admin API is disabled"}`, http.StatusForbidden)
+			return
+		}
+
+		// Сравниваем за постоянное время, чтобы токен нельзя было подобрать по задержкам
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			http.Error(w, `{"error": "Admin authentication required"}`, http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,289 @@
+// Package autobackup takes periodic compressed snapshots of the database
+// and keeps the most recent of them.
+package autobackup
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/db"
+)
+
+const (
+	filePrefix = "scheduler-"
+	fileSuffix = ".db.gz"
+	// nameLayout sorts backups chronologically by file name
+	nameLayout = "20060102-150405"
+)
+
+// File describes a stored backup
+type File struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Status describes the backup schedule and the outcome of the latest run
+type Status struct {
+	Enabled     bool       `json:"enabled"`
+	Interval    string     `json:"interval"`
+	Keep        int        `json:"keep"`
+	Running     bool       `json:"running"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastFile    string     `json:"last_file,omitempty"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+}
+
+var (
+	mu     sync.Mutex
+	status Status
+	cancel context.CancelFunc
+	done   chan struct{}
+)
+
+// Start launches the backup goroutine unless backups are disabled
+// with a zero TODO_BACKUP_INTERVAL
+func Start() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	interval := config.AppConfig.BackupInterval
+	status = Status{
+		Enabled:  interval > 0,
+		Interval: interval.String(),
+		Keep:     config.AppConfig.BackupKeep,
+	}
+	if interval <= 0 || cancel != nil {
+		return
+	}
+
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	done = make(chan struct{})
+	next := firstRun(interval)
+	status.NextRun = &next
+	go loop(ctx, interval, next, done)
+}
+
+// Stop cancels a backup in progress and waits for the goroutine to exit
+func Stop() {
+	mu.Lock()
+	stop, finished := cancel, done
+	cancel, done = nil, nil
+	mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	stop()
+	<-finished
+}
+
+// GetStatus returns the current schedule state
+func GetStatus() Status {
+	mu.Lock()
+	defer mu.Unlock()
+	return status
+}
+
+func loop(ctx context.Context, interval time.Duration, next time.Time, done chan<- struct{}) {
+	defer close(done)
+
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			run(ctx)
+			timer.Reset(interval)
+			setNextRun(time.Now().Add(interval))
+		}
+	}
+}
+
+// firstRun schedules the first backup an interval after the newest stored one,
+// so that a server restarted more often than the interval still takes backups.
+// Without stored backups the first one is taken right away.
+func firstRun(interval time.Duration) time.Time {
+	now := time.Now()
+	files, err := List()
+	if err != nil {
+		log.Println("Error listing backups:", err)
+		return now
+	}
+	if len(files) == 0 {
+		return now
+	}
+	next := files[0].CreatedAt.Add(interval)
+	// A backup stamped in the future means the clock was moved back
+	if next.After(now.Add(interval)) {
+		return now.Add(interval)
+	}
+	return next
+}
+
+func setNextRun(t time.Time) {
+	mu.Lock()
+	status.NextRun = &t
+	mu.Unlock()
+}
+
+// run takes one backup and records the outcome in the status
+func run(ctx context.Context) {
+	started := time.Now()
+	mu.Lock()
+	status.Running = true
+	status.LastRun = &started
+	mu.Unlock()
+
+	name, err := Create(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	status.Running = false
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("Scheduled backup failed:", err)
+		}
+		status.LastError = err.Error()
+		return
+	}
+	status.LastError = ""
+	status.LastSuccess = &started
+	status.LastFile = name
+}
+
+// Create takes a snapshot of the database, verifies it, compresses it into
+// the backup directory and removes backups beyond the retention count.
+// It returns the name of the new backup file.
+func Create(ctx context.Context) (string, error) {
+	dir := config.AppConfig.BackupDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := filePrefix + time.Now().Format(nameLayout) + fileSuffix
+	snapshot := filepath.Join(dir, strings.TrimSuffix(name, ".gz")+".tmp")
+	defer os.Remove(snapshot)
+
+	if err := db.Backup(ctx, snapshot); err != nil {
+		return "", fmt.Errorf("snapshot: %w", err)
+	}
+	if err := db.Verify(snapshot); err != nil {
+		return "", fmt.Errorf("verify: %w", err)
+	}
+	if err := compress(ctx, snapshot, filepath.Join(dir, name)); err != nil {
+		return "", fmt.Errorf("compress: %w", err)
+	}
+	if err := rotate(dir, config.AppConfig.BackupKeep); err != nil {
+		return "", fmt.Errorf("rotate: %w", err)
+	}
+	return name, nil
+}
+
+// compress gzips src into dst. The data goes to a temporary file that is
+// renamed into place, so an interrupted run never leaves a truncated backup.
+func compress(ctx context.Context, src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(dst), ".backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, contextReader{ctx, in})
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dst)
+}
+
+// contextReader stops a copy once ctx is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// rotate removes the oldest backups so that at most keep remain; keep <= 0 keeps all
+func rotate(dir string, keep int) error {
+	files, err := List()
+	if err != nil || keep <= 0 || len(files) <= keep {
+		return err
+	}
+	for _, f := range files[keep:] {
+		if err := os.Remove(filepath.Join(dir, f.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the stored backups, newest first
+func List() ([]File, error) {
+	entries, err := os.ReadDir(config.AppConfig.BackupDir)
+	if os.IsNotExist(err) {
+		return []File{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files := []File{}
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(name, filePrefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, fileSuffix)
+		if !ok {
+			continue
+		}
+		created, err := time.ParseInLocation(nameLayout, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: name, Size: info.Size(), CreatedAt: created})
+	}
+
+	slices.SortFunc(files, func(a, b File) int { return strings.Compare(b.Name, a.Name) })
+	return files, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+// errUsage reports a malformed command line; the usage text has already been printed
+var errUsage = errors.New("invalid arguments")
+
+// runCommand executes a command-line subcommand against the configured database
+func runCommand(args []string) error {
+	switch args[0] {
+	case "backup":
+		return runBackup(args[1:])
+	case "restore":
+		return runRestore(args[1:])
+	case "merge":
+		return runMerge(args[1:])
+	default:
+		printUsage()
+		return errUsage
+	}
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: %[1]s [command]
+
+Without a command the HTTP server is started.
+
+Commands:
+  backup <file>    write a consistent snapshot of the database to file
+  restore <file>   replace the database contents with a backup file
+  merge [-dry-run] [-match title,date,repeat] <file>
+                   add the tasks of another scheduler database, skipping the ones
+                   that match a stored task in all -match fields
+`, os.Args[0])
+}
+
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 {
+		printUsage()
+		return errUsage
+	}
+
+	db.InitDB()
+	defer db.CloseDB()
+
+	if err := db.Backup(context.Background(), fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Println("Backup written to", fs.Arg(0))
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 {
+		printUsage()
+		return errUsage
+	}
+
+	// Restore opens the file as a database, which would create a missing one
+	if _, err := os.Stat(fs.Arg(0)); err != nil {
+		return err
+	}
+
+	db.InitDB()
+	defer db.CloseDB()
+
+	if err := db.Restore(fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Println("Database restored from", fs.Arg(0))
+	return nil
+}
+
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only report what would be merged")
+	match := fs.String("match", strings.Join(taskdb.DefaultMatch, ","), "fields that make two tasks duplicates")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != 1 {
+		printUsage()
+		return errUsage
+	}
+
+	db.InitDB()
+	defer db.CloseDB()
+
+	src, cleanup, err := db.OpenCopy(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	doc, err := taskdb.ExportFrom(src)
+	if err != nil {
+		return err
+	}
+	attachments, err := taskdb.AttachmentCountsFrom(src)
+	if err != nil {
+		return err
+	}
+	opts := taskdb.ImportOptions{
+		Mode:        taskdb.ImportMerge,
+		DryRun:      *dryRun,
+		SkipInvalid: true,
+		Match:       strings.Split(*match, ","),
+	}
+	result, err := taskdb.ImportTasks(doc, opts)
+	if err != nil {
+		return err
+	}
+	// The files live next to the other database, so attachments are only reported
+	for i, t := range doc.Tasks {
+		if n := attachments[t.ID]; n > 0 {
+			result.Warnings = append(result.Warnings, taskdb.ImportIssue{Index: i, Title: t.Title, Message: fmt.Sprintf("attachments not merged: %d", n)})
+		}
+	}
+	printMergeResult(fs.Arg(0), result)
+	return nil
+}
+
+// printMergeResult reports the counts of a merge and lists the tasks that need a look
+func printMergeResult(path string, result taskdb.ImportResult) {
+	verb := "Merged"
+	if result.DryRun {
+		verb = "Would merge"
+	}
+	fmt.Printf("%s %s: %d tasks created, %d duplicates skipped, %d conflicts skipped, %d invalid, %d projects created\n",
+		verb, path, result.Created, len(result.Duplicates), len(result.Conflicts), result.Invalid, result.ProjectsCreated)
+
+	for _, section := range []struct {
+		title  string
+		issues []taskdb.ImportIssue
+	}{
+		{"Conflicts, the stored tasks were kept:", result.Conflicts},
+		{"Invalid tasks, not merged:", result.Errors},
+		{"Attachments stay in the other database, copy the files by hand:", result.Warnings},
+	} {
+		if len(section.issues) == 0 {
+			continue
+		}
+		fmt.Println(section.title)
+		for _, issue := range section.issues {
+			fmt.Printf("  %q: %s\n", issue.Title, issue.Message)
+		}
+	}
+	if result.DryRun {
+		fmt.Println("Dry run, nothing was written")
+	}
+}
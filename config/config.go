@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -11,6 +12,23 @@ type Config struct {
 	Port     string `envconfig:"TODO_PORT" default:"7540"`
 	DBFile   string `envconfig:"TODO_DBFILE" default:"scheduler.db"`
 	Password string `envconfig:"TODO_PASSWORD" required:"true"`
+
+	AttachmentsDir    string `envconfig:"TODO_ATTACHMENTS_DIR" default:"attachments"`
+	MaxAttachmentSize int64  `envconfig:"TODO_MAX_ATTACHMENT_SIZE" default:"10485760"` // bytes
+
+	// RequireIfMatch makes PUT and DELETE of a task fail without an If-Match header
+	RequireIfMatch bool `envconfig:"TODO_REQUIRE_IF_MATCH" default:"false"`
+
+	// AdminToken protects /api/admin/*; the admin API is disabled while it is empty
+	AdminToken     string `envconfig:"TODO_ADMIN_TOKEN"`
+	MaxRestoreSize int64  `envconfig:"TODO_MAX_RESTORE_SIZE" default:"104857600"` // bytes
+
+	MaxImportSize int64 `envconfig:"TODO_MAX_IMPORT_SIZE" default:"10485760"` // bytes
+
+	// Scheduled backups; a zero interval turns them off, a zero keep never deletes old ones
+	BackupDir      string        `envconfig:"TODO_BACKUP_DIR" default:"backups"`
+	BackupInterval time.Duration `envconfig:"TODO_BACKUP_INTERVAL" default:"24h"`
+	BackupKeep     int           `envconfig:"TODO_BACKUP_KEEP" default:"7"`
 }
 
 var AppConfig Config
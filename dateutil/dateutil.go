@@ -2,6 +2,7 @@ package dateutil
 
 import (
 	"errors"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -9,6 +10,9 @@ import (
 
 const DateLayout = "20060102"
 
+// DisplayLayout — формат даты для людей: в лентах и документах
+const DisplayLayout = "02.01.2006"
+
 // NextDate вычисляет следующую дату на основе указанного правила повторения
 func NextDate(now time.Time, date string, repeat string) (string, error) {
 	if repeat == "" {
@@ -49,38 +53,37 @@ func NextDate(now time.Time, date string, repeat string) (string, error) {
 		}
 
 	case strings.HasPrefix(repeat, "m "):
+		if err := ValidateRepeat(repeat); err != nil {
+			return "", err
+		}
 		parts := strings.Split(repeat, " ")
-		daysStr := parts[1]
-		var days []int
-
-		dayParts := strings.Split(daysStr, ",")
-		for _, day := range dayParts {
-			dayInt, err := strconv.Atoi(day)
-			if err != nil {
-				return "", errors.New("неверное значение дня месяца")
-			}
-			days = append(days, dayInt)
+		days := numbers(parts[1])
+		var months []int
+		if len(parts) == 3 {
+			months = numbers(parts[2])
 		}
 
+		// Начинаем с более поздней из дат: перебирать дни до now незачем
 		nextDate := startDate
-		for {
-			if nextDate.After(now) {
-				break
+		if now.After(nextDate) {
+			nextDate = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, startDate.Location())
+		}
+		// За восемь лет встречается любой день любого месяца, даже 29 февраля
+		for i := 0; i < 8*366; i++ {
+			nextDate = nextDate.AddDate(0, 0, 1)
+			if !nextDate.After(now) || !nextDate.After(startDate) {
+				continue
 			}
-			nextDate = nextDate.AddDate(0, 0, 1) // Увеличиваем день
-			for _, day := range days {
-				if day == -1 {
-					nextDate = time.Date(nextDate.Year(), nextDate.Month(), 1, 0, 0, 0, 0, nextDate.Location())
-					nextDate = nextDate.AddDate(0, 1, -1) // последний день месяца
-				} else if day == -2 {
-					nextDate = time.Date(nextDate.Year(), nextDate.Month(), 1, 0, 0, 0, 0, nextDate.Location())
-					nextDate = nextDate.AddDate(0, 1, -2) // предпоследний день месяца
-				} else if nextDate.Day() == day {
-					return nextDate.Format(DateLayout), nil
-				}
+			if len(months) > 0 && !slices.Contains(months, int(nextDate.Month())) {
+				continue
+			}
+			// Номер дня с конца месяца: -1 — последний день, -2 — предпоследний
+			fromEnd := nextDate.Day() - time.Date(nextDate.Year(), nextDate.Month()+1, 1, 0, 0, 0, 0, nextDate.Location()).AddDate(0, 0, -1).Day() - 1
+			if slices.Contains(days, nextDate.Day()) || slices.Contains(days, fromEnd) {
+				return nextDate.Format(DateLayout), nil
 			}
 		}
-		return nextDate.Format(DateLayout), nil
+		return "", errors.New("в правиле m нет существующих дат")
 
 	case strings.HasPrefix(repeat, "w "):
 		parts := strings.Split(repeat, " ")
@@ -114,3 +117,176 @@ func NextDate(now time.Time, date string, repeat string) (string, error) {
 		return "", errors.New("неподдерживаемый формат")
 	}
 }
+
+// ValidateRepeat проверяет правило повторения, не вычисляя дату.
+// Пустое правило допустимо: задача разовая.
+func ValidateRepeat(repeat string) error {
+	if repeat == "" || repeat == "y" {
+		return nil
+	}
+
+	parts := strings.Split(repeat, " ")
+	switch parts[0] {
+	case "d":
+		if len(parts) != 2 {
+			return errors.New("неверный формат правила d")
+		}
+		days, err := strconv.Atoi(parts[1])
+		if err != nil || days < 1 || days > 400 {
+			return errors.New("неверное значение дней в правиле d")
+		}
+		return nil
+
+	case "w":
+		if len(parts) != 2 {
+			return errors.New("неверный формат правила w")
+		}
+		return checkNumbers(parts[1], "неверное значение дня недели", func(n int) bool { return n >= 1 && n <= 7 })
+
+	case "m":
+		if len(parts) != 2 && len(parts) != 3 {
+			return errors.New("неверный формат правила m")
+		}
+		err := checkNumbers(parts[1], "неверное значение дня месяца", func(n int) bool {
+			return (n >= 1 && n <= 31) || n == -1 || n == -2
+		})
+		if err != nil || len(parts) == 2 {
+			return err
+		}
+		return checkNumbers(parts[2], "неверное значение месяца", func(n int) bool { return n >= 1 && n <= 12 })
+
+	default:
+		return errors.New("неподдерживаемый формат")
+	}
+}
+
+// checkNumbers проверяет, что list — числа через запятую, для которых ok истинно
+func checkNumbers(list, msg string, ok func(int) bool) error {
+	for _, s := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil || !ok(n) {
+			return errors.New(msg)
+		}
+	}
+	return nil
+}
+
+var weekdayNames = []string{"", "пн", "вт", "ср", "чт", "пт", "сб", "вс"}
+
+var monthNames = []string{"", "янв", "фев", "мар", "апр", "май", "июн", "июл", "авг", "сен", "окт", "ноя", "дек"}
+
+// FormatDate переводит дату YYYYMMDD в DisplayLayout; неверная дата возвращается как есть
+func FormatDate(date string) string {
+	t, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return date
+	}
+	return t.Format(DisplayLayout)
+}
+
+// DescribeRepeat описывает правило повторения словами, например «по дням недели: пн, ср».
+// Неверное правило возвращается как есть.
+func DescribeRepeat(repeat string) string {
+	if ValidateRepeat(repeat) != nil {
+		return repeat
+	}
+
+	parts := strings.Split(repeat, " ")
+	switch parts[0] {
+	case "y":
+		return "каждый год"
+	case "d":
+		if parts[1] == "1" {
+			return "каждый день"
+		}
+		return "раз в " + parts[1] + " дн."
+	case "w":
+		return "по дням недели: " + describeNumbers(parts[1], func(n int) string { return weekdayNames[n] })
+	case "m":
+		text := "по числам месяца: " + describeNumbers(parts[1], func(n int) string {
+			switch n {
+			case -1:
+				return "последнее"
+			case -2:
+				return "предпоследнее"
+			}
+			return strconv.Itoa(n)
+		})
+		if len(parts) == 3 {
+			text += "; месяцы: " + describeNumbers(parts[2], func(n int) string { return monthNames[n] })
+		}
+		return text
+	}
+	return repeat
+}
+
+// numbers разбирает проверенный список чисел через запятую
+func numbers(list string) []int {
+	var result []int
+	for _, s := range strings.Split(list, ",") {
+		n, _ := strconv.Atoi(s)
+		result = append(result, n)
+	}
+	return result
+}
+
+// describeNumbers заменяет числа из проверенного списка их названиями
+func describeNumbers(list string, name func(int) string) string {
+	numbers := strings.Split(list, ",")
+	for i, s := range numbers {
+		n, _ := strconv.Atoi(s)
+		numbers[i] = name(n)
+	}
+	return strings.Join(numbers, ", ")
+}
+
+// maxOccurrences ограничивает число наступлений, которые Occurrences вычисляет для одной задачи
+const maxOccurrences = 1000
+
+// Occurrences возвращает даты наступлений задачи с датой date и правилом repeat
+// в промежутке from–to включительно (все даты — YYYYMMDD). У разовой задачи
+// наступление одно — её дата. Следующие даты вычисляются через NextDate.
+func Occurrences(date, repeat, from, to string) ([]string, error) {
+	if repeat == "" {
+		if date >= from && date <= to {
+			return []string{date}, nil
+		}
+		return nil, nil
+	}
+	if err := ValidateRepeat(repeat); err != nil {
+		return nil, err
+	}
+	fromDate, err := time.Parse(DateLayout, from)
+	if err != nil {
+		return nil, errors.New("неверный формат даты")
+	}
+
+	current := date
+	if current < from {
+		// Перескакиваем к первому наступлению не раньше from
+		current, err = NextDate(fromDate.AddDate(0, 0, -1), date, repeat)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var dates []string
+	for current <= to && len(dates) < maxOccurrences {
+		if current >= from {
+			dates = append(dates, current)
+		}
+		now, err := time.Parse(DateLayout, current)
+		if err != nil {
+			return nil, err
+		}
+		next, err := NextDate(now, current, repeat)
+		if err != nil {
+			return nil, err
+		}
+		if next <= current {
+			break
+		}
+		current = next
+	}
+	return dates, nil
+}
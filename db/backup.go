@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrInvalidBackup is returned by Restore and Verify for a file that is not a usable scheduler database
+var ErrInvalidBackup = errors.New("not a valid scheduler database")
+
+// requiredColumns are the scheduler columns every release of the database has had
+var requiredColumns = []string{"id", "date", "title", "comment", "repeat"}
+
+// Backup writes a consistent snapshot of the database to a new file at path.
+// VACUUM INTO reads inside one transaction, so concurrent writes never tear the copy.
+func Backup(ctx context.Context, path string) error {
+	_, err := db.ExecContext(ctx, "VACUUM INTO ?", path)
+	return err
+}
+
+// Verify checks that the database file at path is intact and holds the scheduler table
+func Verify(path string) error {
+	conn, err := open(path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return checkBackup(conn)
+}
+
+// Restore replaces the contents of the database with the database file at path.
+// The file is checked and its schema brought up to date first, then its pages
+// are copied over the live database with the SQLite online backup API, so
+// open connections keep working and see either the old or the new data.
+func Restore(path string) error {
+	src, err := open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := checkBackup(src); err != nil {
+		return err
+	}
+	if _, err := createDatabaseIfNotExist(src); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	dstConn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+
+	return dstConn.Raw(func(dst any) error {
+		return srcConn.Raw(func(src any) error {
+			backup, err := dst.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// OpenCopy opens a temporary copy of the scheduler database file at path with its
+// schema brought up to date, so that a file from an older release can be read like
+// the live database while the file itself stays untouched. cleanup closes the copy
+// and deletes it.
+func OpenCopy(path string) (conn *sql.DB, cleanup func(), err error) {
+	if _, err := os.Stat(path); err != nil {
+		// Opening a missing file would create it
+		return nil, nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	// In a URI the path is percent-encoded, so '?', '#' and '%' in it stay part of the name
+	uri := url.URL{Scheme: "file", Path: filepath.ToSlash(abs), RawQuery: "mode=ro&_busy_timeout=5000"}
+	src, err := sql.Open("sqlite3", uri.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer src.Close()
+	if err := checkBackup(src); err != nil {
+		return nil, nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "scheduler-copy")
+	if err != nil {
+		return nil, nil, err
+	}
+	copyPath := filepath.Join(dir, "scheduler.db")
+	if _, err := src.Exec("VACUUM INTO ?", copyPath); err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+
+	conn, err = open(copyPath)
+	if err == nil {
+		_, err = createDatabaseIfNotExist(conn)
+	}
+	cleanup = func() {
+		if conn != nil {
+			conn.Close()
+		}
+		os.RemoveAll(dir)
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return conn, cleanup, nil
+}
+
+// checkBackup verifies that conn holds an intact database with the scheduler table
+func checkBackup(conn *sql.DB) error {
+	var result string
+	if err := conn.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		// Files that aren't SQLite databases at all fail here
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", ErrInvalidBackup, result)
+	}
+
+	for _, column := range requiredColumns {
+		var exists bool
+		query := "SELECT EXISTS (SELECT 1 FROM pragma_table_info('scheduler') WHERE name = ?)"
+		if err := conn.QueryRow(query, column).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: scheduler.%s is missing", ErrInvalidBackup, column)
+		}
+	}
+	return nil
+}
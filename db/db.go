@@ -2,30 +2,77 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/deeramster/go_final_project/config"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 var db *sql.DB
 
+// writer shares the database file with db, but its transactions are immediate
+var writer *sql.DB
+
+// driverName is the sqlite3 driver with the application's SQL functions
+const driverName = "sqlite3_scheduler"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("casefold", casefold, true)
+		},
+	})
+}
+
+// casefold lowers text for case-insensitive LIKE: the built-in LIKE only
+// folds ASCII letters, so "Отчёт" would not match "отчёт". NULL stays NULL.
+func casefold(value any) any {
+	switch v := value.(type) {
+	case string:
+		return strings.ToLower(v)
+	case []byte:
+		return strings.ToLower(string(v))
+	}
+	return value
+}
+
+// ftsEnabled reports whether the FTS5 search index is available
+var ftsEnabled bool
+
 // InitDB opens a connection to the database
 func InitDB() {
 	var err error
 	if db == nil {
-		db, err = sql.Open("sqlite3", config.AppConfig.DBFile)
+		db, err = open(config.AppConfig.DBFile)
+		if err != nil {
+			log.Fatal("Error opening DB:", err)
+		}
+		writer, err = sql.Open(driverName, config.AppConfig.DBFile+"?_busy_timeout=5000&_txlock=immediate")
 		if err != nil {
 			log.Fatal("Error opening DB:", err)
 		}
 		// Initialize the database schema if necessary
-		createDatabaseIfNotExist()
+		ftsEnabled, err = createDatabaseIfNotExist(db)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 }
 
+// open opens the database file at path.
+// Writers wait for each other instead of failing with "database is locked".
+func open(path string) (*sql.DB, error) {
+	return sql.Open(driverName, path+"?_busy_timeout=5000")
+}
+
 // createDatabaseIfNotExist ensures that the database and tables are created
-func createDatabaseIfNotExist() {
+// and brings an older schema up to date. fts reports whether the search index
+// is available; only InitDB records it, so preparing other files leaves the
+// state of the live database alone.
+func createDatabaseIfNotExist(conn *sql.DB) (fts bool, err error) {
 	query := `
   CREATE TABLE IF NOT EXISTS scheduler (
    id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -35,11 +82,212 @@ func createDatabaseIfNotExist() {
    repeat TEXT
   );
   CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);
+
+  CREATE TABLE IF NOT EXISTS projects (
+   id INTEGER PRIMARY KEY AUTOINCREMENT,
+   name TEXT NOT NULL,
+   color TEXT NOT NULL DEFAULT '',
+   position INTEGER NOT NULL DEFAULT 0
+  );
+
+  CREATE TABLE IF NOT EXISTS subtasks (
+   id INTEGER PRIMARY KEY AUTOINCREMENT,
+   task_id INTEGER NOT NULL REFERENCES scheduler(id),
+   title TEXT NOT NULL,
+   done INTEGER NOT NULL DEFAULT 0,
+   position INTEGER NOT NULL DEFAULT 0
+  );
+  CREATE INDEX IF NOT EXISTS idx_subtasks_task ON subtasks(task_id, position);
+  CREATE TRIGGER IF NOT EXISTS scheduler_subtasks_ad AFTER DELETE ON scheduler BEGIN
+   DELETE FROM subtasks WHERE task_id = old.id;
+  END;
+
+  CREATE TABLE IF NOT EXISTS task_dependencies (
+   task_id INTEGER NOT NULL REFERENCES scheduler(id),
+   blocker_id INTEGER NOT NULL REFERENCES scheduler(id),
+   PRIMARY KEY (task_id, blocker_id)
+  );
+  CREATE INDEX IF NOT EXISTS idx_dependencies_blocker ON task_dependencies(blocker_id);
+  -- A deleted task no longer blocks anything, so its dependents may become ready
+  CREATE TRIGGER IF NOT EXISTS scheduler_dependencies_ad AFTER DELETE ON scheduler BEGIN
+   DELETE FROM task_dependencies WHERE task_id = old.id OR blocker_id = old.id;
+  END;
+
+  CREATE TABLE IF NOT EXISTS attachments (
+   id INTEGER PRIMARY KEY AUTOINCREMENT,
+   task_id INTEGER NOT NULL REFERENCES scheduler(id),
+   name TEXT NOT NULL,
+   content_type TEXT NOT NULL,
+   size INTEGER NOT NULL,
+   stored_name TEXT NOT NULL UNIQUE,
+   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+  );
+  CREATE INDEX IF NOT EXISTS idx_attachments_task ON attachments(task_id);
+  CREATE TRIGGER IF NOT EXISTS scheduler_attachments_ad AFTER DELETE ON scheduler BEGIN
+   DELETE FROM attachments WHERE task_id = old.id;
+  END;
+
+  CREATE TABLE IF NOT EXISTS tags (
+   id INTEGER PRIMARY KEY AUTOINCREMENT,
+   name TEXT NOT NULL UNIQUE
+  );
+  CREATE TABLE IF NOT EXISTS task_tags (
+   task_id INTEGER NOT NULL REFERENCES scheduler(id),
+   tag_id INTEGER NOT NULL REFERENCES tags(id),
+   PRIMARY KEY (task_id, tag_id)
+  );
+  CREATE INDEX IF NOT EXISTS idx_task_tags_tag ON task_tags(tag_id);
+  -- Links go away with their task, tags with their last link
+  CREATE TRIGGER IF NOT EXISTS scheduler_tags_ad AFTER DELETE ON scheduler BEGIN
+   DELETE FROM task_tags WHERE task_id = old.id;
+  END;
+  CREATE TRIGGER IF NOT EXISTS task_tags_ad AFTER DELETE ON task_tags
+  WHEN NOT EXISTS (SELECT 1 FROM task_tags WHERE tag_id = old.tag_id) BEGIN
+   DELETE FROM tags WHERE id = old.tag_id;
+  END;
+
+  -- Secret tokens of machine feeds (calendar subscriptions and the like)
+  CREATE TABLE IF NOT EXISTS feed_tokens (
+   feed TEXT PRIMARY KEY,
+   token TEXT NOT NULL UNIQUE
+  );
+
+  -- Names and UIDs CalDAV clients gave to the tasks they created; rows
+  -- outlive their task so that sync can still name deleted resources
+  CREATE TABLE IF NOT EXISTS caldav_resources (
+   task_id INTEGER PRIMARY KEY,
+   name TEXT NOT NULL UNIQUE,
+   uid TEXT NOT NULL
+  );
  `
-	_, err := db.Exec(query)
+	_, err = conn.Exec(query)
 	if err != nil {
-		log.Fatal("Error creating table:", err)
+		return false, fmt.Errorf("error creating table: %w", err)
+	}
+
+	if err := migrateSchema(conn); err != nil {
+		return false, err
 	}
+	return createSearchIndex(conn)
+}
+
+// migrateSchema adds columns introduced after the first release to existing databases
+func migrateSchema(conn *sql.DB) error {
+	columns := []struct{ name, definition string }{
+		{"project_id", "INTEGER REFERENCES projects(id)"},
+		{"priority", "INTEGER NOT NULL DEFAULT 0"},
+		{"version", "INTEGER NOT NULL DEFAULT 1"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfNotExist(conn, "scheduler", c.name, c.definition); err != nil {
+			return err
+		}
+	}
+
+	_, err := conn.Exec(`
+  CREATE INDEX IF NOT EXISTS idx_project ON scheduler(project_id);
+  -- Every change of a task row bumps its version for optimistic concurrency
+  CREATE TRIGGER IF NOT EXISTS scheduler_version_au AFTER UPDATE ON scheduler
+  WHEN new.version = old.version BEGIN
+   UPDATE scheduler SET version = old.version + 1 WHERE id = new.id;
+  END;
+
+  -- The latest change of every task, numbered for CalDAV sync tokens;
+  -- a deleted task keeps its row as a tombstone
+  CREATE TABLE IF NOT EXISTS task_changes (
+   seq INTEGER PRIMARY KEY AUTOINCREMENT,
+   task_id INTEGER NOT NULL UNIQUE,
+   deleted INTEGER NOT NULL DEFAULT 0
+  );
+  CREATE TRIGGER IF NOT EXISTS scheduler_changes_ai AFTER INSERT ON scheduler BEGIN
+   INSERT OR REPLACE INTO task_changes (task_id) VALUES (new.id);
+  END;
+  CREATE TRIGGER IF NOT EXISTS scheduler_changes_au AFTER UPDATE ON scheduler
+  WHEN new.version <> old.version BEGIN
+   INSERT OR REPLACE INTO task_changes (task_id) VALUES (new.id);
+  END;
+  CREATE TRIGGER IF NOT EXISTS scheduler_changes_ad AFTER DELETE ON scheduler BEGIN
+   INSERT OR REPLACE INTO task_changes (task_id, deleted) VALUES (old.id, 1);
+  END;
+ `)
+	if err != nil {
+		return fmt.Errorf("error creating index: %w", err)
+	}
+	return nil
+}
+
+// addColumnIfNotExist adds a column unless the table already has it
+func addColumnIfNotExist(conn *sql.DB, table, column, definition string) error {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM pragma_table_info(?) WHERE name = ?)"
+	if err := conn.QueryRow(query, table, column).Scan(&exists); err != nil {
+		return fmt.Errorf("error reading table info: %w", err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := conn.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition); err != nil {
+		return fmt.Errorf("error adding column: %w", err)
+	}
+	return nil
+}
+
+// createSearchIndex sets up the FTS5 index over task titles and comments.
+// FTS5 is only compiled in with the sqlite_fts5 build tag, so without it the
+// sync triggers are dropped and search falls back to LIKE; the result
+// reports whether the index is available.
+func createSearchIndex(conn *sql.DB) (bool, error) {
+	_, err := conn.Exec(`
+  CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_fts USING fts5(
+   title,
+   comment,
+   content='scheduler',
+   content_rowid='id',
+   tokenize='unicode61 remove_diacritics 2'
+  );
+ `)
+	if err != nil {
+		log.Println("Full-text search is unavailable, falling back to LIKE:", err)
+		_, err = conn.Exec(`
+  DROP TRIGGER IF EXISTS scheduler_fts_ai;
+  DROP TRIGGER IF EXISTS scheduler_fts_ad;
+  DROP TRIGGER IF EXISTS scheduler_fts_au;
+ `)
+		if err != nil {
+			return false, fmt.Errorf("error dropping search triggers: %w", err)
+		}
+		return false, nil
+	}
+
+	// Missing triggers mean the index is new or went stale while FTS5 was off
+	var triggers int
+	err = conn.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'scheduler_fts_%'`).Scan(&triggers)
+	if err != nil {
+		return false, fmt.Errorf("error checking search triggers: %w", err)
+	}
+
+	_, err = conn.Exec(`
+  CREATE TRIGGER IF NOT EXISTS scheduler_fts_ai AFTER INSERT ON scheduler BEGIN
+   INSERT INTO scheduler_fts(rowid, title, comment) VALUES (new.id, new.title, new.comment);
+  END;
+  CREATE TRIGGER IF NOT EXISTS scheduler_fts_ad AFTER DELETE ON scheduler BEGIN
+   INSERT INTO scheduler_fts(scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+  END;
+  CREATE TRIGGER IF NOT EXISTS scheduler_fts_au AFTER UPDATE OF title, comment ON scheduler BEGIN
+   INSERT INTO scheduler_fts(scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+   INSERT INTO scheduler_fts(rowid, title, comment) VALUES (new.id, new.title, new.comment);
+  END;
+ `)
+	if err != nil {
+		return false, fmt.Errorf("error creating search triggers: %w", err)
+	}
+
+	if triggers < 3 {
+		if _, err := conn.Exec(`INSERT INTO scheduler_fts(scheduler_fts) VALUES ('rebuild')`); err != nil {
+			return false, fmt.Errorf("error building search index: %w", err)
+		}
+	}
+	return true, nil
 }
 
 // GetDB returns the active database connection
@@ -47,8 +295,24 @@ func GetDB() *sql.DB {
 	return db
 }
 
+// BeginWrite starts a transaction that is going to write. It takes the write
+// lock up front, so a transaction that reads before writing cannot deadlock
+// with another one; transactions started with GetDB().Begin() only read
+// until they write and let other readers in.
+func BeginWrite() (*sql.Tx, error) {
+	return writer.Begin()
+}
+
+// FTSEnabled reports whether full-text search can be used
+func FTSEnabled() bool {
+	return ftsEnabled
+}
+
 // CloseDB closes the database connection when the application stops
 func CloseDB() {
+	if writer != nil {
+		writer.Close()
+	}
 	if db != nil {
 		err := db.Close()
 		if err != nil {
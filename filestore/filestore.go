@@ -0,0 +1,68 @@
+package filestore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/deeramster/go_final_project/config"
+)
+
+// ErrTooLarge is returned by Save when the content exceeds the size limit
+var ErrTooLarge = errors.New("file is too large")
+
+// Save writes r to a new file in the attachments directory and returns its
+// stored name and size. Stored names are random, so client file names never reach the disk.
+func Save(r io.Reader, limit int64) (string, int64, error) {
+	if err := os.MkdirAll(config.AppConfig.AttachmentsDir, 0o755); err != nil {
+		return "", 0, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", 0, err
+	}
+	name := hex.EncodeToString(buf)
+
+	f, err := os.OpenFile(path(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Reading one byte past the limit tells an exact fit from an overflow
+	size, err := io.Copy(f, io.LimitReader(r, limit+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > limit {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		Remove(name)
+		return "", 0, err
+	}
+	return name, size, nil
+}
+
+// Open opens a stored file for reading
+func Open(name string) (*os.File, error) {
+	return os.Open(path(name))
+}
+
+// Remove deletes stored files; failures are logged since the metadata is already gone
+func Remove(names ...string) {
+	for _, name := range names {
+		if err := os.Remove(path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Println("Error removing attachment file:", err)
+		}
+	}
+}
+
+// path resolves a stored name inside the attachments directory
+func path(name string) string {
+	return filepath.Join(config.AppConfig.AttachmentsDir, filepath.Base(name))
+}
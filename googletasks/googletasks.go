@@ -0,0 +1,137 @@
+// Package googletasks reads the Tasks.json file of a Google Takeout export.
+//
+// Every task list becomes a project, subtasks become checklist items of
+// their parent and notes become the comment. Takeout keeps no recurrence
+// and due dates without a time of day, so tasks are imported as one-off.
+package googletasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/deeramster/go_final_project/models"
+)
+
+// Issue describes a task that could not be read or was read with losses;
+// JSON has no lines, so tasks are told apart by their title
+type Issue struct {
+	Title   string
+	Message string
+}
+
+// export is the layout of Tasks.json
+type export struct {
+	Kind  string     `json:"kind"`
+	Items []taskList `json:"items"`
+}
+
+type taskList struct {
+	Title string `json:"title"`
+	Items []task `json:"items"`
+}
+
+type task struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Notes   string `json:"notes"`
+	Status  string `json:"status"`
+	Due     string `json:"due"`
+	Parent  string `json:"parent"`
+	Deleted bool   `json:"deleted"`
+	Links   []any  `json:"links"`
+}
+
+// ErrNotTakeout is returned for JSON that isn't a Google Tasks export
+var ErrNotTakeout = errors.New("not a Google Tasks export")
+
+// Read converts a Tasks.json file to an export document ready for import.
+// Completed and deleted tasks are skipped with a warning, unreadable ones are
+// reported in errs and left out, losses go to warnings. Tasks without a due
+// date are due on today.
+func Read(r io.Reader) (doc models.Export, errs, warnings []Issue, err error) {
+	doc = models.Export{Format: models.ExportFormat, Version: models.ExportVersion, Projects: []models.ExportProject{}, Tasks: []models.ExportTask{}}
+
+	var file export
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return doc, nil, nil, err
+	}
+	if file.Kind != "tasks#taskLists" {
+		return doc, nil, nil, ErrNotTakeout
+	}
+
+	today := time.Now().Format(dateutil.DateLayout)
+	for i, list := range file.Items {
+		projectID := int64(i + 1)
+		doc.Projects = append(doc.Projects, models.ExportProject{ID: projectID, Name: strings.TrimSpace(list.Title), Position: i + 1})
+
+		// Subtasks may come before their parent, so tasks are added first
+		index := make(map[string]int)
+		for _, item := range list.Items {
+			if item.Parent != "" {
+				continue
+			}
+			if msg := skipReason(item); msg != "" {
+				warnings = append(warnings, Issue{Title: item.Title, Message: msg})
+				continue
+			}
+
+			t := models.ExportTask{
+				ID:        strconv.Itoa(len(doc.Tasks) + 1),
+				Date:      today,
+				Title:     strings.TrimSpace(item.Title),
+				Comment:   strings.TrimSpace(item.Notes),
+				ProjectID: &projectID,
+			}
+			if item.Due != "" {
+				due, err := time.Parse(time.RFC3339, item.Due)
+				if err != nil {
+					errs = append(errs, Issue{Title: item.Title, Message: fmt.Sprintf("invalid due date %q", item.Due)})
+					continue
+				}
+				// Google stores a due date as midnight UTC of that day
+				t.Date = due.UTC().Format(dateutil.DateLayout)
+			}
+			if len(item.Links) > 0 {
+				warnings = append(warnings, Issue{Title: item.Title, Message: "links dropped"})
+			}
+			index[item.ID] = len(doc.Tasks)
+			doc.Tasks = append(doc.Tasks, t)
+		}
+
+		for _, item := range list.Items {
+			if item.Parent == "" || item.Deleted {
+				continue
+			}
+			i, ok := index[item.Parent]
+			if !ok {
+				// The parent was completed or deleted
+				continue
+			}
+			doc.Tasks[i].Subtasks = append(doc.Tasks[i].Subtasks, models.ExportSubtask{
+				Title: strings.TrimSpace(item.Title),
+				Done:  item.Status == "completed",
+			})
+			if item.Notes != "" || item.Due != "" {
+				warnings = append(warnings, Issue{Title: item.Title, Message: "notes and due date of a subtask dropped"})
+			}
+		}
+	}
+	return doc, errs, warnings, nil
+}
+
+// skipReason tells why a top-level task isn't imported, or returns ""
+func skipReason(item task) string {
+	switch {
+	case item.Deleted:
+		return "deleted task skipped"
+	case item.Status == "completed":
+		return "completed task skipped"
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deeramster/go_final_project/autobackup"
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/db"
+)
+
+// HandleBackup отдаёт согласованный снимок базы данных (GET)
+func HandleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// VACUUM INTO пишет только в новый файл, поэтому снимок кладём во временный каталог
+	dir, err := os.MkdirTemp("", "scheduler-backup-")
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create backup"}`, http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "scheduler.db")
+	if err := db.Backup(r.Context(), path); err != nil {
+		http.Error(w, `{"error": "Failed to create backup"}`, http.StatusInternalServerError)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to read backup"}`, http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	now := time.Now()
+	name := "scheduler-" + now.Format("20060102-150405") + ".db"
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	http.ServeContent(w, r, name, now, f)
+}
+
+// HandleRestore заменяет базу данных загруженной копией (POST, поле file)
+func HandleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxSize := config.AppConfig.MaxRestoreSize
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, `{"error": "Expected multipart/form-data"}`, http.StatusBadRequest)
+		return
+	}
+
+	part, err := nextFilePart(mr)
+	if err == io.EOF {
+		http.Error(w, `{"error": "File is required"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeRestoreUploadError(w, err)
+		return
+	}
+
+	f, err := os.CreateTemp("", "scheduler-restore-*.db")
+	if err != nil {
+		http.Error(w, `{"error": "Failed to save upload"}`, http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(f.Name())
+
+	// Чтение на байт больше лимита отличает файл ровно по лимиту от превышения
+	size, err := io.Copy(f, io.LimitReader(part, maxSize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > maxSize {
+		err = errRestoreTooLarge
+	}
+	if err != nil {
+		writeRestoreUploadError(w, err)
+		return
+	}
+
+	err = db.Restore(f.Name())
+	if errors.Is(err, db.ErrInvalidBackup) {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to restore database"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeEmptyJSON(w)
+}
+
+var errRestoreTooLarge = errors.New("backup is too large")
+
+// writeRestoreUploadError различает превышение лимита размера и прочие ошибки загрузки
+func writeRestoreUploadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.Is(err, errRestoreTooLarge) || errors.As(err, &maxBytesErr) {
+		http.Error(w, fmt.Sprintf(`{"error": "File exceeds %d bytes"}`, config.AppConfig.MaxRestoreSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, `{"error": "Failed to upload file"}`, http.StatusBadRequest)
+}
+
+// HandleBackups возвращает расписание автоматических копий и список сохранённых файлов (GET)
+func HandleBackups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	files, err := autobackup.List()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to list backups"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(struct {
+		autobackup.Status
+		Backups []autobackup.File `json:"backups"`
+	}{autobackup.GetStatus(), files})
+	if err != nil {
+		return
+	}
+}
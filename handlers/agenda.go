@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+// maxAgendaDays ограничивает длину повестки: повторяющиеся задачи размножаются по каждому дню
+const maxAgendaDays = 366
+
+var weekdayTitles = []string{"Воскресенье", "Понедельник", "Вторник", "Среда", "Четверг", "Пятница", "Суббота"}
+
+// agendaItem — наступление задачи в конкретный день; virtual — будущее повторение, которого ещё нет в базе
+type agendaItem struct {
+	task    models.Task
+	virtual bool
+}
+
+// HandleAgenda отдаёт повестку на дни from–to (YYYYMMDD) в Markdown для ежедневных заметок (GET).
+// По умолчанию — неделя с сегодняшнего дня. Повторяющиеся задачи попадают в каждый день,
+// на который выпадает их повторение.
+func HandleAgenda(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := time.Now()
+	from := now.Format(dateutil.DateLayout)
+	if s := r.URL.Query().Get("from"); s != "" {
+		from = s
+	}
+	fromDate, err := time.Parse(dateutil.DateLayout, from)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid 'from' date format, expected YYYYMMDD"}`, http.StatusBadRequest)
+		return
+	}
+	to := fromDate.AddDate(0, 0, 6).Format(dateutil.DateLayout)
+	if s := r.URL.Query().Get("to"); s != "" {
+		to = s
+	}
+	toDate, err := time.Parse(dateutil.DateLayout, to)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid 'to' date format, expected YYYYMMDD"}`, http.StatusBadRequest)
+		return
+	}
+	if toDate.Before(fromDate) || toDate.Sub(fromDate) >= maxAgendaDays*24*time.Hour {
+		http.Error(w, fmt.Sprintf(`{"error": "'to' must be within %d days after 'from'"}`, maxAgendaDays), http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := taskdb.NewTaskQuery().DateTo(to).All()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load tasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	days := make(map[string][]agendaItem)
+	for _, task := range tasks {
+		dates, err := dateutil.Occurrences(task.Date, task.Repeat, from, to)
+		if err != nil {
+			// Задача с неверным правилом видна хотя бы в свой день
+			dates = nil
+			if task.Date >= from {
+				dates = []string{task.Date}
+			}
+		}
+		for _, date := range dates {
+			days[date] = append(days[date], agendaItem{task: task, virtual: date != task.Date})
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	_, err = io.WriteString(w, agendaMarkdown(fromDate, toDate, days))
+	if err != nil {
+		return
+	}
+}
+
+// agendaMarkdown собирает документ: заголовок с периодом и по разделу на каждый день с задачами
+func agendaMarkdown(from, to time.Time, days map[string][]agendaItem) string {
+	var b strings.Builder
+	b.WriteString("# Повестка: " + from.Format(dateutil.DisplayLayout))
+	if !to.Equal(from) {
+		b.WriteString(" — " + to.Format(dateutil.DisplayLayout))
+	}
+	b.WriteString("\n")
+
+	dates := make([]string, 0, len(days))
+	for date := range days {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	if len(dates) == 0 {
+		b.WriteString("\nЗадач нет.\n")
+	}
+
+	for _, date := range dates {
+		day, _ := time.Parse(dateutil.DateLayout, date)
+		b.WriteString("\n## " + weekdayTitles[day.Weekday()] + ", " + day.Format(dateutil.DisplayLayout) + "\n\n")
+		for _, item := range days[date] {
+			b.WriteString("- [ ] " + markdownLine(item.task.Title))
+			if item.task.Repeat != "" {
+				note := dateutil.DescribeRepeat(item.task.Repeat)
+				if item.virtual {
+					note = "будущее повторение, " + note
+				}
+				b.WriteString(" _(" + note + ")_")
+			}
+			for _, tag := range item.task.Tags {
+				b.WriteString(" #" + strings.ReplaceAll(tag, " ", "_"))
+			}
+			b.WriteString("\n")
+
+			// Комментарий — отступ внутри пункта списка, пустые строки не разрывают список
+			for _, line := range strings.Split(item.task.Comment, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					b.WriteString("  " + markdownCommentLine(line) + "\n")
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// markdownEscaper экранирует символы, которые Markdown внутри строки принял бы за разметку:
+// выделение, ссылки, код, HTML, сущности, таблицы и метки
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "~", `\~`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "&", `\&`, "#", `\#`, "|", `\|`,
+)
+
+// markdownLine превращает заголовок в одну строку с экранированной разметкой,
+// чтобы он не ломал список и показывался как написан
+func markdownLine(s string) string {
+	return markdownEscaper.Replace(strings.Join(strings.Fields(s), " "))
+}
+
+// markdownBlockStart находит в начале строки маркер списка, черту или подчёркивание заголовка
+var markdownBlockStart = regexp.MustCompile(`^([-+=]|\d+[.)])`)
+
+// markdownCommentLine экранирует строку комментария: кроме разметки внутри строки,
+// её начало не должно открыть вложенный список или превратить пункт в заголовок
+func markdownCommentLine(line string) string {
+	line = markdownEscaper.Replace(line)
+	if loc := markdownBlockStart.FindStringIndex(line); loc != nil {
+		// Обратная косая ставится перед последним символом маркера: \-, 12\.
+		line = line[:loc[1]-1] + `\` + line[loc[1]-1:]
+	}
+	return line
+}
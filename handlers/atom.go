@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+const (
+	// defaultAtomDays — на сколько дней вперёд лента показывает задачи по умолчанию
+	defaultAtomDays = 7
+	// maxAtomDays ограничивает горизонт ленты, чтобы она не превращалась в выгрузку всех задач
+	maxAtomDays = 365
+)
+
+// atomFeed — лента Atom (RFC 4287)
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// HandleAtomFeed отдаёт ленту Atom с просроченными задачами и задачами на ближайшие days дней (GET).
+// Каждое наступление повторяющейся задачи — отдельная запись, поэтому читалка покажет его как новое.
+func HandleAtomFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := defaultAtomDays
+	if s := r.URL.Query().Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > maxAtomDays {
+			http.Error(w, `{"error": "Days must be a number from 0 to 365"}`, http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	tasks, err := taskdb.NewTaskQuery().DateTo(today.AddDate(0, 0, days).Format(dateutil.DateLayout)).All()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load tasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	feed := atomFeed{
+		ID:      "urn:go_final_project:tasks",
+		Title:   calendarName + ": ближайшие и просроченные задачи",
+		Updated: now.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: calendarName},
+		Entries: make([]atomEntry, 0, len(tasks)),
+	}
+	for _, task := range tasks {
+		date, err := time.ParseInLocation(dateutil.DateLayout, task.Date, time.Local)
+		if err != nil {
+			continue
+		}
+
+		status := "upcoming"
+		lines := []string{"Срок: " + dateutil.FormatDate(task.Date)}
+		if date.Before(today) {
+			status = "overdue"
+			lines[0] += " (просрочено)"
+		}
+		if task.Repeat != "" {
+			lines = append(lines, "Повторение: "+dateutil.DescribeRepeat(task.Repeat))
+		}
+		if task.Comment != "" {
+			lines = append(lines, "", task.Comment)
+		}
+
+		entry := atomEntry{
+			ID:    "urn:go_final_project:task:" + task.ID + ":" + task.Date,
+			Title: task.Title,
+			// Запись появляется в ленте, когда задача попадает в горизонт, и с тех пор не меняется
+			Updated:    date.AddDate(0, 0, -days).UTC().Format(time.RFC3339),
+			Categories: []atomCategory{{Term: status}},
+			Content:    atomContent{Type: "text", Text: strings.Join(lines, "\n")},
+		}
+		for _, tag := range task.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return
+	}
+	err = xml.NewEncoder(w).Encode(feed)
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/filestore"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+// multipartOverhead — запас на заголовки multipart сверх размера самого файла
+const multipartOverhead = 1 << 20
+
+// HandleTaskAttachments возвращает список вложений задачи (GET) и загружает новое (POST, поле file)
+func HandleTaskAttachments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	taskID, ok := parseExistingTaskID(w, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		handleAttachmentUpload(w, r, taskID)
+		return
+	}
+
+	attachments, err := taskdb.GetAttachments(taskID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load attachments"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string][]models.Attachment{"attachments": attachments})
+	if err != nil {
+		return
+	}
+}
+
+func handleAttachmentUpload(w http.ResponseWriter, r *http.Request, taskID int) {
+	maxSize := config.AppConfig.MaxAttachmentSize
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, `{"error": "Expected multipart/form-data"}`, http.StatusBadRequest)
+		return
+	}
+
+	part, err := nextFilePart(mr)
+	if err == io.EOF {
+		http.Error(w, `{"error": "File is required"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	name := filepath.Base(part.FileName())
+	body := bufio.NewReaderSize(part, 512)
+	contentType := part.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		head, _ := body.Peek(512)
+		contentType = http.DetectContentType(head)
+	}
+
+	storedName, size, err := filestore.Save(body, maxSize)
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	id, err := taskdb.AddAttachment(models.Attachment{
+		TaskID:      strconv.Itoa(taskID),
+		Name:        name,
+		ContentType: contentType,
+		Size:        size,
+		StoredName:  storedName,
+	})
+	if err != nil {
+		filestore.Remove(storedName)
+		http.Error(w, `{"error": "Failed to save attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int64{"id": id})
+	if err != nil {
+		return
+	}
+}
+
+// nextFilePart находит часть с файлом в поле file, не сохраняя форму целиком во временные файлы
+func nextFilePart(mr *multipart.Reader) (*multipart.Part, error) {
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			return part, nil
+		}
+	}
+}
+
+// writeUploadError различает превышение лимита размера и прочие ошибки загрузки
+func writeUploadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.Is(err, filestore.ErrTooLarge) || errors.As(err, &maxBytesErr) {
+		http.Error(w, fmt.Sprintf(`{"error": "File exceeds %d bytes"}`, config.AppConfig.MaxAttachmentSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, `{"error": "Failed to upload file"}`, http.StatusBadRequest)
+}
+
+// HandleAttachment отдаёт файл вложения (GET) или удаляет его (DELETE)
+func HandleAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err = taskdb.DeleteAttachment(id)
+		if errors.Is(err, taskdb.ErrAttachmentNotFound) {
+			http.Error(w, `{"error": "Attachment not found"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error": "Failed to delete attachment"}`, http.StatusInternalServerError)
+			return
+		}
+		writeEmptyJSON(w)
+		return
+	}
+
+	attachment, err := taskdb.GetAttachmentByID(id)
+	if errors.Is(err, taskdb.ErrAttachmentNotFound) {
+		http.Error(w, `{"error": "Attachment not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	f, err := filestore.Open(attachment.StoredName)
+	if err != nil {
+		http.Error(w, `{"error": "Attachment file is missing"}`, http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to read attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Тип содержимого берём из метаданных, чтобы браузер не угадывал его сам
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	http.ServeContent(w, r, attachment.Name, info.ModTime(), f)
+}
@@ -0,0 +1,766 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/deeramster/go_final_project/ical"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+const (
+	// calDAVRoot — корень CalDAV: он же принципал и домашняя коллекция календарей
+	calDAVRoot = "/caldav/"
+	// calDAVCalendar — единственный календарь, в нём все задачи
+	calDAVCalendar = calDAVRoot + "tasks/"
+	// syncTokenPrefix превращает номер изменения в URI, как требует RFC 6578
+	syncTokenPrefix = "urn:scheduler:sync:"
+)
+
+// Пространства имён XML и их префиксы в ответах
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+)
+
+var nsPrefixes = map[string]string{nsDAV: "d", nsCalDAV: "c", nsCS: "cs"}
+
+// davRequest — тело PROPFIND или REPORT; какие поля заполнены, зависит от запроса
+type davRequest struct {
+	XMLName   xml.Name
+	AllProp   *struct{} `xml:"DAV: allprop"`
+	PropName  *struct{} `xml:"DAV: propname"`
+	Prop      *davProp  `xml:"DAV: prop"`
+	Hrefs     []string  `xml:"DAV: href"`
+	SyncToken *string   `xml:"DAV: sync-token"`
+	Filter    *struct {
+		CompFilter struct {
+			CompFilters []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// davProp — список запрошенных свойств
+type davProp struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+// davProperty — свойство ресурса с готовым XML-содержимым
+type davProperty struct {
+	name  xml.Name
+	inner string
+}
+
+// davResource — ресурс CalDAV со всеми свойствами, которые мы умеем отдавать
+type davResource struct {
+	href  string
+	props []davProperty
+}
+
+// davResponse — элемент response в ответе multistatus.
+// status без свойств означает, что ресурса нет (например, удалённая задача при синхронизации).
+type davResponse struct {
+	href    string
+	found   []davProperty
+	missing []xml.Name
+	status  int
+}
+
+// HandleCalDAV — минимальный сервер CalDAV для синхронизации задач (VTODO) с телефонами.
+// /caldav/ — принципал и домашняя коллекция, /caldav/tasks/ — календарь задач,
+// /caldav/tasks/<имя>.ics — отдельная задача. Поддерживаются PROPFIND, REPORT
+// (calendar-query, calendar-multiget, sync-collection), GET, PUT и DELETE.
+// Задача, пришедшая со STATUS:COMPLETED, выполняется так же, как через /api/task/done.
+func HandleCalDAV(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	var name string
+	switch {
+	case path == calDAVRoot, path == calDAVCalendar:
+	case path+"/" == calDAVCalendar:
+		path = calDAVCalendar
+	case strings.HasPrefix(path, calDAVCalendar) && strings.HasSuffix(path, ".ics"):
+		name = strings.TrimSuffix(strings.TrimPrefix(path, calDAVCalendar), ".ics")
+		if name == "" || strings.Contains(name, "/") {
+			http.NotFound(w, r)
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodOptions:
+		w.Header().Set("DAV", "1, 3, calendar-access")
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "PROPFIND":
+		handleCalDAVPropfind(w, r, path, name)
+	case r.Method == "REPORT" && path == calDAVCalendar:
+		handleCalDAVReport(w, r)
+	case (r.Method == http.MethodGet || r.Method == http.MethodHead) && name != "":
+		handleCalDAVGet(w, name)
+	case r.Method == http.MethodPut && name != "":
+		handleCalDAVPut(w, r, name)
+	case r.Method == http.MethodDelete && name != "":
+		handleCalDAVDelete(w, r, name)
+	default:
+		w.Header().Set("Allow", "OPTIONS, PROPFIND, REPORT, GET, HEAD, PUT, DELETE")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func handleCalDAVPropfind(w http.ResponseWriter, r *http.Request, path, name string) {
+	req, ok := readDAVRequest(w, r)
+	if !ok {
+		return
+	}
+	// По умолчанию глубина бесконечна; глубже одного уровня у нас ничего нет
+	children := r.Header.Get("Depth") != "0"
+
+	var resources []davResource
+	switch {
+	case name != "":
+		task, err := taskdb.FindCalDAVTask(name)
+		if errors.Is(err, taskdb.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error": "Failed to load task"}`, http.StatusInternalServerError)
+			return
+		}
+		res, err := taskResource(task)
+		if err != nil {
+			http.Error(w, `{"error": "Failed to encode task"}`, http.StatusInternalServerError)
+			return
+		}
+		resources = append(resources, res)
+
+	case path == calDAVRoot:
+		resources = append(resources, rootResource())
+		if children {
+			res, err := calendarResource()
+			if err != nil {
+				http.Error(w, `{"error": "Failed to read sync token"}`, http.StatusInternalServerError)
+				return
+			}
+			resources = append(resources, res)
+		}
+
+	default:
+		res, err := calendarResource()
+		if err != nil {
+			http.Error(w, `{"error": "Failed to read sync token"}`, http.StatusInternalServerError)
+			return
+		}
+		resources = append(resources, res)
+		if children {
+			tasks, err := taskdb.CalDAVTasks()
+			if err != nil {
+				http.Error(w, `{"error": "Failed to load tasks"}`, http.StatusInternalServerError)
+				return
+			}
+			taskResources, err := taskResources(tasks)
+			if err != nil {
+				http.Error(w, `{"error": "Failed to encode tasks"}`, http.StatusInternalServerError)
+				return
+			}
+			resources = append(resources, taskResources...)
+		}
+	}
+
+	responses := make([]davResponse, len(resources))
+	for i, res := range resources {
+		responses[i] = selectProps(res, req)
+	}
+	writeMultistatus(w, responses, "")
+}
+
+func handleCalDAVReport(w http.ResponseWriter, r *http.Request) {
+	req, ok := readDAVRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var responses []davResponse
+	var syncToken string
+	switch req.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		// Фильтры по времени не поддерживаются: отдаём все задачи, если спрашивают про VTODO
+		if req.Filter != nil && len(req.Filter.CompFilter.CompFilters) > 0 && !queriesTodos(req) {
+			writeMultistatus(w, nil, "")
+			return
+		}
+		tasks, err := taskdb.CalDAVTasks()
+		if err != nil {
+			http.Error(w, `{"error": "Failed to load tasks"}`, http.StatusInternalServerError)
+			return
+		}
+		resources, err := taskResources(tasks)
+		if err != nil {
+			http.Error(w, `{"error": "Failed to encode tasks"}`, http.StatusInternalServerError)
+			return
+		}
+		for _, res := range resources {
+			responses = append(responses, selectProps(res, req))
+		}
+
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		for _, href := range req.Hrefs {
+			res, err := hrefResource(href)
+			if errors.Is(err, taskdb.ErrNotFound) {
+				responses = append(responses, davResponse{href: href, status: http.StatusNotFound})
+				continue
+			}
+			if err != nil {
+				http.Error(w, `{"error": "Failed to load tasks"}`, http.StatusInternalServerError)
+				return
+			}
+			responses = append(responses, selectProps(res, req))
+		}
+
+	case xml.Name{Space: nsDAV, Local: "sync-collection"}:
+		var err error
+		responses, syncToken, err = syncCollection(req)
+		if errors.Is(err, errInvalidSyncToken) {
+			writeDAVError(w, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "valid-sync-token"})
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error": "Failed to load changes"}`, http.StatusInternalServerError)
+			return
+		}
+
+	default:
+		writeDAVError(w, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "supported-report"})
+		return
+	}
+	writeMultistatus(w, responses, syncToken)
+}
+
+// errInvalidSyncToken — токен не наш или из будущего (например, после восстановления базы)
+var errInvalidSyncToken = errors.New("invalid sync token")
+
+// syncCollection отдаёт изменения после токена клиента: изменённые задачи со свойствами
+// и удалённые со статусом 404. Без токена отдаются все задачи.
+func syncCollection(req davRequest) ([]davResponse, string, error) {
+	latest, err := taskdb.SyncToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var since int64
+	if req.SyncToken != nil && strings.TrimSpace(*req.SyncToken) != "" {
+		seq, ok := strings.CutPrefix(strings.TrimSpace(*req.SyncToken), syncTokenPrefix)
+		since, err = strconv.ParseInt(seq, 10, 64)
+		if !ok || err != nil || since < 0 || since > latest {
+			return nil, "", errInvalidSyncToken
+		}
+	}
+
+	var tasks []taskdb.CalDAVTask
+	var deleted []string
+	if since == 0 {
+		tasks, err = taskdb.CalDAVTasks()
+	} else {
+		tasks, deleted, err = taskdb.TaskChanges(since, latest)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	resources, err := taskResources(tasks)
+	if err != nil {
+		return nil, "", err
+	}
+	var responses []davResponse
+	for _, res := range resources {
+		responses = append(responses, selectProps(res, req))
+	}
+	for _, name := range deleted {
+		responses = append(responses, davResponse{href: taskHref(name), status: http.StatusNotFound})
+	}
+	return responses, syncTokenPrefix + strconv.FormatInt(latest, 10), nil
+}
+
+func handleCalDAVGet(w http.ResponseWriter, name string) {
+	task, err := taskdb.FindCalDAVTask(name)
+	if errors.Is(err, taskdb.ErrNotFound) {
+		http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load task"}`, http.StatusInternalServerError)
+		return
+	}
+
+	data, err := taskCalendar(task)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to encode task"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", taskETag(task.Version))
+	_, err = w.Write(data)
+	if err != nil {
+		return
+	}
+}
+
+func handleCalDAVPut(w http.ResponseWriter, r *http.Request, name string) {
+	r.Body = http.MaxBytesReader(w, r.Body, config.AppConfig.MaxImportSize)
+	calendar, err := ical.Decode(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, "Invalid iCalendar: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+	var todo *ical.Component
+	for _, c := range calendar.Components {
+		if c.Name == ical.KindTodo {
+			todo = c
+			break
+		}
+	}
+	if todo == nil {
+		writeDAVError(w, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "supported-calendar-component"})
+		return
+	}
+
+	existing, err := taskdb.FindCalDAVTask(name)
+	found := err == nil
+	if err != nil && !errors.Is(err, taskdb.ErrNotFound) {
+		http.Error(w, `{"error": "Failed to load task"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// If-None-Match: * — только создать, If-Match — только изменить
+	var version int64
+	if found {
+		if r.Header.Get("If-None-Match") == "*" {
+			writeVersionMismatch(w)
+			return
+		}
+		var ok bool
+		version, _, ok = parseIfMatch(w, r)
+		if !ok {
+			return
+		}
+		if version != 0 && version != existing.Version {
+			writeVersionMismatch(w)
+			return
+		}
+	} else if r.Header.Get("If-Match") != "" {
+		writeVersionMismatch(w)
+		return
+	}
+
+	// Ответы на PUT идут без ETag: задача хранится не в присланном виде (своё RRULE, DUE, SEQUENCE,
+	// перенос повторяющейся), а RFC 4791, 5.3.4 запрещает ETag, если сохранённый объект отличается.
+	// Клиент узнаёт новый ETag, перечитав задачу.
+	switch {
+	case todoCompleted(todo) && found:
+		completeCalDAVTask(w, existing)
+	case todoCompleted(todo):
+		// Выполненные задачи не хранятся, создавать нечего
+		w.WriteHeader(http.StatusCreated)
+	case found:
+		updateCalDAVTask(w, todo, existing, version)
+	default:
+		createCalDAVTask(w, todo, name)
+	}
+}
+
+// todoCompleted сообщает, что клиент отметил задачу выполненной
+func todoCompleted(todo *ical.Component) bool {
+	status, _ := todo.Get("STATUS")
+	_, completed := todo.Get("COMPLETED")
+	return completed || strings.EqualFold(status.Value, "COMPLETED")
+}
+
+// calDAVTaskFromTodo переводит VTODO в задачу; метки задаются всегда, чтобы удалённые в клиенте метки исчезли
+func calDAVTaskFromTodo(w http.ResponseWriter, todo *ical.Component) (models.Task, bool) {
+	t, _, err := ical.TaskFromComponent(todo, time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadRequest)
+		return models.Task{}, false
+	}
+	if t.Title == "" {
+		http.Error(w, `{"error": "Title is required"}`, http.StatusBadRequest)
+		return models.Task{}, false
+	}
+
+	task := models.Task{Date: t.Date, Title: t.Title, Comment: t.Comment, Repeat: t.Repeat, Tags: []string{}, Priority: &t.Priority}
+	task.Tags = append(task.Tags, t.Tags...)
+	return task, true
+}
+
+func createCalDAVTask(w http.ResponseWriter, todo *ical.Component, name string) {
+	task, ok := calDAVTaskFromTodo(w, todo)
+	if !ok {
+		return
+	}
+	uid, _ := todo.Get("UID")
+
+	_, err := taskdb.AddCalDAVTask(task, name, uid.Value)
+	if errors.Is(err, taskdb.ErrResourceExists) {
+		http.Error(w, `{"error": "Resource name is taken"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to add task"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func updateCalDAVTask(w http.ResponseWriter, todo *ical.Component, existing taskdb.CalDAVTask, version int64) {
+	task, ok := calDAVTaskFromTodo(w, todo)
+	if !ok {
+		return
+	}
+	task.ID = existing.ID
+	task.Version = version
+
+	_, err := taskdb.UpdateTaskInDB(task)
+	if errors.Is(err, taskdb.ErrVersionMismatch) {
+		writeVersionMismatch(w)
+		return
+	}
+	if errors.Is(err, taskdb.ErrNotFound) {
+		http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to update task"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// completeCalDAVTask выполняет задачу: разовая удаляется, повторяющаяся переносится на следующую дату.
+// Задачу с незавершёнными блокирующими задачами закрыть нельзя, как и в POST /api/task/done без force.
+func completeCalDAVTask(w http.ResponseWriter, existing taskdb.CalDAVTask) {
+	id, err := strconv.Atoi(existing.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID format"}`, http.StatusInternalServerError)
+		return
+	}
+	if len(existing.BlockedBy) > 0 {
+		writeBlocked(w, existing.BlockedBy)
+		return
+	}
+
+	var nextDate string
+	if existing.Repeat != "" {
+		nextDate, err = dateutil.NextDate(time.Now(), existing.Date, existing.Repeat)
+		if err != nil {
+			http.Error(w, `{"error": "Failed to calculate next date"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = taskdb.MarkTaskAsDone(id, existing.Date, nextDate)
+	if errors.Is(err, taskdb.ErrAlreadyDone) {
+		writeVersionMismatch(w)
+		return
+	}
+	if errors.Is(err, taskdb.ErrNotFound) {
+		http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to mark task as done"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleCalDAVDelete(w http.ResponseWriter, r *http.Request, name string) {
+	task, err := taskdb.FindCalDAVTask(name)
+	if errors.Is(err, taskdb.ErrNotFound) {
+		http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load task"}`, http.StatusInternalServerError)
+		return
+	}
+	version, _, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(task.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID format"}`, http.StatusInternalServerError)
+		return
+	}
+	err = taskdb.DeleteTaskFromDB(id, version)
+	if errors.Is(err, taskdb.ErrVersionMismatch) {
+		writeVersionMismatch(w)
+		return
+	}
+	if errors.Is(err, taskdb.ErrNotFound) {
+		http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to delete task"}`, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readDAVRequest разбирает XML-тело запроса; пустое тело означает allprop
+func readDAVRequest(w http.ResponseWriter, r *http.Request) (davRequest, bool) {
+	var req davRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, `{"error": "Failed to read request"}`, http.StatusBadRequest)
+		return req, false
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		req.AllProp = &struct{}{}
+		return req, true
+	}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		http.Error(w, `{"error": "Invalid XML"}`, http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// queriesTodos сообщает, есть ли VTODO среди компонентов фильтра calendar-query
+func queriesTodos(req davRequest) bool {
+	for _, f := range req.Filter.CompFilter.CompFilters {
+		if strings.EqualFold(f.Name, ical.KindTodo) {
+			return true
+		}
+	}
+	return false
+}
+
+// hrefResource находит задачу по ссылке из calendar-multiget
+func hrefResource(href string) (davResource, error) {
+	u, err := url.Parse(href)
+	if err != nil || !strings.HasPrefix(u.Path, calDAVCalendar) || !strings.HasSuffix(u.Path, ".ics") {
+		return davResource{}, taskdb.ErrNotFound
+	}
+	task, err := taskdb.FindCalDAVTask(strings.TrimSuffix(strings.TrimPrefix(u.Path, calDAVCalendar), ".ics"))
+	if err != nil {
+		return davResource{}, err
+	}
+	return taskResource(task)
+}
+
+func rootResource() davResource {
+	return davResource{href: calDAVRoot, props: []davProperty{
+		{xml.Name{Space: nsDAV, Local: "resourcetype"}, `<d:collection/><d:principal/>`},
+		{xml.Name{Space: nsDAV, Local: "displayname"}, escapeXML(calendarName)},
+		{xml.Name{Space: nsDAV, Local: "current-user-principal"}, hrefXML(calDAVRoot)},
+		{xml.Name{Space: nsDAV, Local: "principal-URL"}, hrefXML(calDAVRoot)},
+		{xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}, hrefXML(calDAVRoot)},
+	}}
+}
+
+func calendarResource() (davResource, error) {
+	token, err := taskdb.SyncToken()
+	if err != nil {
+		return davResource{}, err
+	}
+	seq := strconv.FormatInt(token, 10)
+
+	var privileges, reports strings.Builder
+	for _, p := range []string{"read", "write", "write-content", "bind", "unbind"} {
+		privileges.WriteString("<d:privilege><d:" + p + "/></d:privilege>")
+	}
+	for _, report := range []string{"c:calendar-query", "c:calendar-multiget", "d:sync-collection"} {
+		reports.WriteString("<d:supported-report><d:report><" + report + "/></d:report></d:supported-report>")
+	}
+
+	return davResource{href: calDAVCalendar, props: []davProperty{
+		{xml.Name{Space: nsDAV, Local: "resourcetype"}, `<d:collection/><c:calendar/>`},
+		{xml.Name{Space: nsDAV, Local: "displayname"}, escapeXML(calendarName)},
+		{xml.Name{Space: nsDAV, Local: "current-user-principal"}, hrefXML(calDAVRoot)},
+		{xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}, privileges.String()},
+		{xml.Name{Space: nsDAV, Local: "supported-report-set"}, reports.String()},
+		{xml.Name{Space: nsDAV, Local: "sync-token"}, syncTokenPrefix + seq},
+		{xml.Name{Space: nsCS, Local: "getctag"}, seq},
+		{xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}, `<c:comp name="VTODO"/>`},
+		{xml.Name{Space: nsCalDAV, Local: "supported-calendar-data"}, `<c:calendar-data content-type="text/calendar" version="2.0"/>`},
+	}}, nil
+}
+
+func taskResource(task taskdb.CalDAVTask) (davResource, error) {
+	data, err := taskCalendar(task)
+	if err != nil {
+		return davResource{}, err
+	}
+	return davResource{href: taskHref(task.Name), props: []davProperty{
+		{xml.Name{Space: nsDAV, Local: "resourcetype"}, ""},
+		{xml.Name{Space: nsDAV, Local: "getetag"}, escapeXML(taskETag(task.Version))},
+		{xml.Name{Space: nsDAV, Local: "getcontenttype"}, "text/calendar; charset=utf-8; component=VTODO"},
+		{xml.Name{Space: nsCalDAV, Local: "calendar-data"}, escapeXML(string(data))},
+	}}, nil
+}
+
+func taskResources(tasks []taskdb.CalDAVTask) ([]davResource, error) {
+	resources := make([]davResource, 0, len(tasks))
+	for _, task := range tasks {
+		res, err := taskResource(task)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, res)
+	}
+	return resources, nil
+}
+
+// taskCalendar возвращает задачу как VCALENDAR с одним VTODO и тем UID, с которым её создал клиент
+func taskCalendar(task taskdb.CalDAVTask) ([]byte, error) {
+	todo := ical.TaskComponent(task.Task, ical.KindTodo, time.Now())
+	if task.UID != "" {
+		todo.Set("UID", task.UID)
+	}
+	calendar := ical.Calendar(calendarName)
+	calendar.Components = append(calendar.Components, todo)
+
+	var buf bytes.Buffer
+	err := ical.Encode(&buf, calendar)
+	return buf.Bytes(), err
+}
+
+func taskHref(name string) string {
+	return calDAVCalendar + url.PathEscape(name) + ".ics"
+}
+
+// selectProps оставляет в ответе запрошенные свойства ресурса; отсутствующие уходят в propstat с 404.
+// calendar-data слишком велико для allprop и отдаётся только по запросу.
+func selectProps(res davResource, req davRequest) davResponse {
+	resp := davResponse{href: res.href}
+	switch {
+	case req.PropName != nil:
+		for _, p := range res.props {
+			resp.found = append(resp.found, davProperty{name: p.name})
+		}
+	case req.Prop == nil:
+		for _, p := range res.props {
+			if p.name.Local != "calendar-data" {
+				resp.found = append(resp.found, p)
+			}
+		}
+	default:
+	next:
+		for _, n := range req.Prop.Names {
+			for _, p := range res.props {
+				if p.name == n.XMLName {
+					resp.found = append(resp.found, p)
+					continue next
+				}
+			}
+			resp.missing = append(resp.missing, n.XMLName)
+		}
+	}
+	return resp
+}
+
+// writeMultistatus отвечает 207 Multi-Status; syncToken добавляется в ответ sync-collection
+func writeMultistatus(w http.ResponseWriter, responses []davResponse, syncToken string) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="` + nsCalDAV + `" xmlns:cs="` + nsCS + `">`)
+	for _, resp := range responses {
+		b.WriteString("<d:response>" + hrefXML(resp.href))
+		if resp.status != 0 {
+			b.WriteString(statusXML(resp.status))
+		}
+		if len(resp.found) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, p := range resp.found {
+				b.WriteString(elementXML(p.name, p.inner))
+			}
+			b.WriteString("</d:prop>" + statusXML(http.StatusOK) + "</d:propstat>")
+		}
+		if len(resp.missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range resp.missing {
+				b.WriteString(elementXML(name, ""))
+			}
+			b.WriteString("</d:prop>" + statusXML(http.StatusNotFound) + "</d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	if syncToken != "" {
+		b.WriteString("<d:sync-token>" + escapeXML(syncToken) + "</d:sync-token>")
+	}
+	b.WriteString("</d:multistatus>\n")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return
+	}
+}
+
+// writeDAVError отвечает ошибкой с нарушенным предусловием WebDAV/CalDAV
+func writeDAVError(w http.ResponseWriter, status int, precondition xml.Name) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	body := `<?xml version="1.0" encoding="utf-8"?>` + "\n" +
+		`<d:error xmlns:d="DAV:" xmlns:c="` + nsCalDAV + `">` + elementXML(precondition, "") + "</d:error>\n"
+	_, err := io.WriteString(w, body)
+	if err != nil {
+		return
+	}
+}
+
+// elementXML записывает элемент с готовым содержимым; для чужих пространств имён объявляет своё
+func elementXML(name xml.Name, inner string) string {
+	tag, attrs := name.Local, ""
+	if prefix, ok := nsPrefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		tag = "x:" + name.Local
+		attrs = ` xmlns:x="` + strings.ReplaceAll(escapeXML(name.Space), `"`, "&quot;") + `"`
+	}
+	if inner == "" {
+		return "<" + tag + attrs + "/>"
+	}
+	return "<" + tag + attrs + ">" + inner + "</" + tag + ">"
+}
+
+func hrefXML(href string) string {
+	return "<d:href>" + escapeXML(href) + "</d:href>"
+}
+
+func statusXML(status int) string {
+	return "<d:status>HTTP/1.1 " + strconv.Itoa(status) + " " + http.StatusText(status) + "</d:status>"
+}
+
+// xmlEscaper экранирует текст элемента. Кавычки остаются как есть, чтобы ETag читался,
+// а \r сохраняется явно, иначе парсер превратит CRLF в iCalendar в LF.
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#13;")
+
+func escapeXML(s string) string {
+	return xmlEscaper.Replace(s)
+}
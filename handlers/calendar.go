@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/deeramster/go_final_project/ical"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+// calendarName — название календаря, которое клиенты показывают у подписки
+const calendarName = "Планировщик"
+
+// HandleCalendar отдаёт задачи в формате iCalendar для подписки в календаре (GET).
+// По умолчанию задачи — события на весь день, с component=todo — задачи VTODO.
+// Правила повторения переводятся в RRULE.
+func HandleCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	kind := ical.KindEvent
+	switch r.URL.Query().Get("component") {
+	case "", "event":
+	case "todo":
+		kind = ical.KindTodo
+	default:
+		http.Error(w, `{"error": "Component must be 'event' or 'todo'"}`, http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := taskdb.NewTaskQuery().All()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load tasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	calendar := ical.Calendar(calendarName)
+	now := time.Now()
+	for _, task := range tasks {
+		calendar.Components = append(calendar.Components, ical.TaskComponent(task, kind, now))
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	err = ical.Encode(w, calendar)
+	if err != nil {
+		return
+	}
+}
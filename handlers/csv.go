@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskcsv"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+// HandleExportCSV выгружает задачи в CSV (GET).
+// Параметры: delimiter (по умолчанию запятая, tab — табуляция), date_format,
+// а также имена столбцов: title=, date=, comment=, repeat=, priority=, project=, tags=.
+func HandleExportCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, ok := parseCSVOptions(w, r)
+	if !ok {
+		return
+	}
+
+	doc, err := taskdb.ExportTasks()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to export tasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	name := "scheduler-" + time.Now().Format("20060102") + ".csv"
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	err = taskcsv.Write(w, doc, opts)
+	if err != nil {
+		return
+	}
+}
+
+// HandleImportCSV загружает задачи из CSV (POST, тело запроса или поле file).
+// Принимает те же параметры, что и экспорт, и общие параметры импорта.
+// Строки с ошибками перечисляются в ответе с номерами.
+func HandleImportCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	importOpts, ok := parseImportOptions(w, r)
+	if !ok {
+		return
+	}
+	csvOpts, ok := parseCSVOptions(w, r)
+	if !ok {
+		return
+	}
+	body, ok := importBody(w, r)
+	if !ok {
+		return
+	}
+
+	doc, rows, rowErrors, err := taskcsv.Read(body, csvOpts)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, `{"error": "Import file is too large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, "Invalid CSV: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var issues []taskdb.ImportIssue
+	for _, e := range rowErrors {
+		issues = append(issues, taskdb.ImportIssue{Index: -1, Row: e.Row, Message: e.Message})
+	}
+	result, err := importWithIssues(doc, rows, issues, importOpts)
+	writeImportResult(w, result, err)
+}
+
+// importWithIssues импортирует задачи, прочитанные из файла построчно.
+// rows — номера строк задач, issues — строки, которые не удалось прочитать.
+// Без skip_invalid такие строки отменяют импорт, как и ошибки проверки задач.
+func importWithIssues(doc models.Export, rows []int, issues []taskdb.ImportIssue, opts taskdb.ImportOptions) (taskdb.ImportResult, error) {
+	dryRun := opts.DryRun
+	if len(issues) > 0 && !opts.SkipInvalid {
+		// Прогоняем проверку целиком, чтобы сообщить обо всех ошибках сразу
+		opts.DryRun = true
+	}
+
+	result, err := taskdb.ImportTasks(doc, opts)
+	result.DryRun = dryRun
+	for i := range result.Errors {
+		if idx := result.Errors[i].Index; idx >= 0 && idx < len(rows) {
+			result.Errors[i].Row = rows[idx]
+		}
+	}
+	for _, list := range [][]taskdb.ImportIssue{result.Duplicates, result.Conflicts} {
+		for i := range list {
+			if idx := list[i].Index; idx >= 0 && idx < len(rows) {
+				list[i].Row = rows[idx]
+			}
+		}
+	}
+	if len(issues) == 0 {
+		return result, err
+	}
+
+	result.Errors = append(result.Errors, issues...)
+	slices.SortStableFunc(result.Errors, func(a, b taskdb.ImportIssue) int { return a.Row - b.Row })
+	result.Invalid += len(issues)
+	if err == nil && !opts.SkipInvalid {
+		result = taskdb.ImportResult{Mode: opts.Mode, DryRun: dryRun, Invalid: result.Invalid, Errors: result.Errors}
+		err = taskdb.ErrInvalidImport
+	}
+	return result, err
+}
+
+// parseCSVOptions разбирает разделитель, формат даты и имена столбцов CSV
+func parseCSVOptions(w http.ResponseWriter, r *http.Request) (taskcsv.Options, bool) {
+	q := r.URL.Query()
+	opts := taskcsv.Options{DateFormat: q.Get("date_format"), Columns: make(map[string]string)}
+
+	switch delimiter := q.Get("delimiter"); {
+	case delimiter == "":
+	case delimiter == "tab":
+		opts.Comma = '\t'
+	case utf8.RuneCountInString(delimiter) == 1 && delimiter != `"` && delimiter != "\r" && delimiter != "\n":
+		opts.Comma, _ = utf8.DecodeRuneInString(delimiter)
+	default:
+		http.Error(w, `{"error": "Delimiter must be a single character or 'tab'"}`, http.StatusBadRequest)
+		return opts, false
+	}
+
+	if _, ok := taskcsv.DateFormats[opts.DateFormat]; opts.DateFormat != "" && !ok {
+		http.Error(w, `{"error": "Unknown date_format, use YYYYMMDD, YYYY-MM-DD, YYYY/MM/DD, DD.MM.YYYY, DD/MM/YYYY or MM/DD/YYYY"}`, http.StatusBadRequest)
+		return opts, false
+	}
+
+	for _, field := range taskcsv.Fields {
+		if name := q.Get(field); name != "" {
+			opts.Columns[field] = name
+		}
+	}
+	return opts, true
+}
+
+// importBody возвращает импортируемый файл: поле file формы multipart или всё тело запроса
+func importBody(w http.ResponseWriter, r *http.Request) (io.Reader, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		r.Body = http.MaxBytesReader(w, r.Body, config.AppConfig.MaxImportSize)
+		return r.Body, true
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, config.AppConfig.MaxImportSize+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, `{"error": "Invalid multipart form"}`, http.StatusBadRequest)
+		return nil, false
+	}
+	part, err := nextFilePart(mr)
+	if err == io.EOF {
+		http.Error(w, `{"error": "File is required"}`, http.StatusBadRequest)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to upload file"}`, http.StatusBadRequest)
+		return nil, false
+	}
+	return part, true
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+type dependencyRequest struct {
+	TaskID    string `json:"task_id"`
+	BlockedBy string `json:"blocked_by"`
+}
+
+// HandleDependencies добавляет (POST) и удаляет (DELETE) зависимость «задача ждёт другую задачу»
+func HandleDependencies(w http.ResponseWriter, r *http.Request) {
+	var req dependencyRequest
+	switch r.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		req.TaskID = r.URL.Query().Get("task_id")
+		req.BlockedBy = r.URL.Query().Get("blocked_by")
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	taskID, err := strconv.ParseInt(req.TaskID, 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid task_id"}`, http.StatusBadRequest)
+		return
+	}
+	blockerID, err := strconv.ParseInt(req.BlockedBy, 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid blocked_by"}`, http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		err = taskdb.AddDependency(taskID, blockerID)
+	} else {
+		err = taskdb.RemoveDependency(taskID, blockerID)
+	}
+	switch {
+	case errors.Is(err, taskdb.ErrNotFound):
+		http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, taskdb.ErrDependencyNotFound):
+		http.Error(w, `{"error": "Dependency not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, taskdb.ErrDependencyCycle):
+		http.Error(w, `{"error": "Dependency would create a cycle"}`, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, `{"error": "Failed to update dependencies"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeEmptyJSON(w)
+}
+
+// writeBlocked отвечает 409 со списком задач, которые блокируют выполнение
+func writeBlocked(w http.ResponseWriter, blockedBy []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	err := json.NewEncoder(w).Encode(map[string]any{
+		"error":      "Task is blocked by unfinished tasks",
+		"blocked_by": blockedBy,
+	})
+	if err != nil {
+		return
+	}
+}
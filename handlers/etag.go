@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/deeramster/go_final_project/config"
+)
+
+// taskETag формирует значение заголовка ETag по версии задачи
+func taskETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch разбирает заголовок If-Match.
+// Возвращает ожидаемую версию (0 — любая) и признак наличия заголовка.
+// При ошибке сам пишет ответ и возвращает ok == false.
+func parseIfMatch(w http.ResponseWriter, r *http.Request) (version int64, present, ok bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		if config.AppConfig.RequireIfMatch {
+			http.Error(w, `{"error": "If-Match header is required"}`, http.StatusPreconditionRequired)
+			return 0, false, false
+		}
+		return 0, false, true
+	}
+	if header == "*" {
+		return 0, true, true
+	}
+
+	// Слабые ETag сравниваем так же, как сильные: версия у задачи одна
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		http.Error(w, `{"error": "Invalid If-Match header"}`, http.StatusPreconditionFailed)
+		return 0, true, false
+	}
+	return version, true, true
+}
+
+// writeVersionMismatch сообщает, что задача изменилась с момента чтения
+func writeVersionMismatch(w http.ResponseWriter) {
+	http.Error(w, `{"error": "Task has been modified, reload it and try again"}`, http.StatusPreconditionFailed)
+}
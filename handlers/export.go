@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+// importResponse — результат импорта; при ошибке проверки в нём же перечислены проблемные задачи
+type importResponse struct {
+	Error string `json:"error,omitempty"`
+	taskdb.ImportResult
+	// Preview — задачи, в которые превратился файл чужого формата, при предпросмотре
+	Preview []models.ExportTask `json:"preview,omitempty"`
+}
+
+// HandleExport выгружает все задачи в JSON-документ (GET)
+func HandleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	doc, err := taskdb.ExportTasks()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to export tasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	name := "scheduler-" + time.Now().Format("20060102") + ".json"
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	err = json.NewEncoder(w).Encode(doc)
+	if err != nil {
+		return
+	}
+}
+
+// HandleImport загружает задачи из JSON-документа экспорта (POST).
+// Параметры: mode=merge|replace (по умолчанию merge), dry_run=true — только предпросмотр,
+// skip_invalid=true — импортировать корректные задачи, даже если есть ошибочные.
+func HandleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, ok := parseImportOptions(w, r)
+	if !ok {
+		return
+	}
+
+	var doc models.Export
+	r.Body = http.MaxBytesReader(w, r.Body, config.AppConfig.MaxImportSize)
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, `{"error": "Import document is too large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	result, err := taskdb.ImportTasks(doc, opts)
+	writeImportResult(w, result, err)
+}
+
+// parseImportOptions разбирает общие для всех импортов параметры mode, dry_run и skip_invalid
+func parseImportOptions(w http.ResponseWriter, r *http.Request) (taskdb.ImportOptions, bool) {
+	opts := taskdb.ImportOptions{Mode: taskdb.ImportMode(r.URL.Query().Get("mode"))}
+	switch opts.Mode {
+	case "":
+		opts.Mode = taskdb.ImportMerge
+	case taskdb.ImportMerge, taskdb.ImportReplace:
+	default:
+		http.Error(w, `{"error": "Mode must be 'merge' or 'replace'"}`, http.StatusBadRequest)
+		return opts, false
+	}
+
+	flags := []struct {
+		name  string
+		value *bool
+	}{
+		{"dry_run", &opts.DryRun},
+		{"skip_invalid", &opts.SkipInvalid},
+	}
+	for _, f := range flags {
+		s := r.URL.Query().Get(f.name)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error": "Invalid %s value"}`, f.name), http.StatusBadRequest)
+			return opts, false
+		}
+		*f.value = v
+	}
+
+	// match=title,date — по каким полям задача считается дубликатом уже сохранённой
+	if s := r.URL.Query().Get("match"); s != "" {
+		opts.Match = strings.Split(s, ",")
+		for _, field := range opts.Match {
+			if !slices.Contains(taskdb.DefaultMatch, field) {
+				http.Error(w, `{"error": "Match must list some of title, date and repeat"}`, http.StatusBadRequest)
+				return opts, false
+			}
+		}
+	}
+	return opts, true
+}
+
+// writeImportResult отвечает результатом импорта или ошибкой с перечнем проблемных задач
+func writeImportResult(w http.ResponseWriter, result taskdb.ImportResult, err error) {
+	writeImportPreview(w, result, nil, err)
+}
+
+// writeImportPreview отвечает как writeImportResult, добавляя к ответу прочитанные из файла задачи
+func writeImportPreview(w http.ResponseWriter, result taskdb.ImportResult, preview []models.ExportTask, err error) {
+	status := http.StatusOK
+	resp := importResponse{ImportResult: result, Preview: preview}
+	switch {
+	case errors.Is(err, taskdb.ErrUnsupportedExport):
+		http.Error(w, `{"error": "Unsupported export format or version"}`, http.StatusBadRequest)
+		return
+	case errors.Is(err, taskdb.ErrInvalidImport):
+		status = http.StatusBadRequest
+		resp.Error = "Import has invalid tasks, nothing was imported"
+	case err != nil:
+		http.Error(w, `{"error": "Failed to import tasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+// feedPaths — адреса машинных лент, к которым выдаются токены
+var feedPaths = map[string]string{
+	taskdb.FeedCalendar: "/api/calendar.ics",
+	taskdb.FeedCalDAV:   "/caldav/",
+	taskdb.FeedAtom:     "/api/tasks.atom",
+}
+
+// feedInfo описывает ленту; URL и токен есть только у включённой ленты
+type feedInfo struct {
+	Feed    string `json:"feed"`
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+// HandleFeeds управляет токенами машинных лент:
+// GET — список лент со ссылками, POST ?feed= — выпустить новый токен (старый перестаёт действовать),
+// DELETE ?feed= — отключить ленту.
+func HandleFeeds(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleFeedsGet(w)
+	case http.MethodPost:
+		handleFeedPost(w, r)
+	case http.MethodDelete:
+		handleFeedDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func handleFeedsGet(w http.ResponseWriter) {
+	tokens, err := taskdb.FeedTokens()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load feeds"}`, http.StatusInternalServerError)
+		return
+	}
+
+	feeds := make([]feedInfo, 0, len(taskdb.Feeds))
+	for _, feed := range taskdb.Feeds {
+		info := feedInfo{Feed: feed}
+		if token, ok := tokens[feed]; ok {
+			info.Enabled = true
+			info.URL = feedURL(feed, token)
+			info.Token = token
+		}
+		feeds = append(feeds, info)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string][]feedInfo{"feeds": feeds})
+	if err != nil {
+		return
+	}
+}
+
+func handleFeedPost(w http.ResponseWriter, r *http.Request) {
+	feed := r.URL.Query().Get("feed")
+	token, err := taskdb.RotateFeedToken(feed)
+	if errors.Is(err, taskdb.ErrUnknownFeed) {
+		http.Error(w, `{"error": "Unknown feed"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to issue feed token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(feedInfo{Feed: feed, Enabled: true, URL: feedURL(feed, token), Token: token})
+	if err != nil {
+		return
+	}
+}
+
+func handleFeedDelete(w http.ResponseWriter, r *http.Request) {
+	err := taskdb.RevokeFeedToken(r.URL.Query().Get("feed"))
+	if errors.Is(err, taskdb.ErrUnknownFeed) {
+		http.Error(w, `{"error": "Unknown feed"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to disable feed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeEmptyJSON(w)
+}
+
+// feedURL возвращает путь ленты с токеном.
+// CalDAV-клиенты не сохраняют параметры адреса, токен они передают паролем.
+func feedURL(feed, token string) string {
+	if feed == taskdb.FeedCalDAV {
+		return feedPaths[feed]
+	}
+	return feedPaths[feed] + "?" + url.Values{"token": {token}}.Encode()
+}
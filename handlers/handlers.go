@@ -1,16 +1,19 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/deeramster/go_final_project/dateutil"
 	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/searchlang"
 	"github.com/deeramster/go_final_project/taskdb"
 )
 
@@ -78,6 +81,16 @@ func handleTaskPost(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Приоритет: от 1 (самый срочный) до 4, 0 — без приоритета
+	if task.Priority != nil && (*task.Priority < 0 || *task.Priority > taskdb.MaxPriority) {
+		http.Error(w, `{"error": "Priority must be between 1 and 4, or 0 for none"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !checkTaskProject(w, task) {
+		return
+	}
+
 	// Добавление задачи в базу данных
 	id, err := taskdb.AddTaskToDB(task)
 	if err != nil {
@@ -135,13 +148,42 @@ func handleTaskPut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Приоритет: от 1 (самый срочный) до 4, 0 — без приоритета
+	if task.Priority != nil && (*task.Priority < 0 || *task.Priority > taskdb.MaxPriority) {
+		http.Error(w, `{"error": "Priority must be between 1 and 4, or 0 for none"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !checkTaskProject(w, task) {
+		return
+	}
+
+	// Ожидаемая версия: из If-Match, иначе из тела запроса
+	version, present, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+	if present {
+		task.Version = version
+	}
+
 	// Обновление задачи в базе данных
-	if err := taskdb.UpdateTaskInDB(task); err != nil {
+	task.Version, err = taskdb.UpdateTaskInDB(task)
+	if err != nil {
+		if errors.Is(err, taskdb.ErrVersionMismatch) {
+			writeVersionMismatch(w)
+			return
+		}
+		if errors.Is(err, taskdb.ErrNotFound) {
+			http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Возвращаем успешный ответ
+	w.Header().Set("ETag", taskETag(task.Version))
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(task)
 	if err != nil {
@@ -165,23 +207,26 @@ func handleTaskGet(w http.ResponseWriter, r *http.Request) {
 
 	// Получаем задачу из базы данных
 	task, err := taskdb.GetTaskByID(id)
-	if err != nil {
+	if errors.Is(err, taskdb.ErrNotFound) {
 		http.Error(w, `{"error": "Задача не найдена"}`, http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load task"}`, http.StatusInternalServerError)
+		return
+	}
 
-	// Формируем ответ в формате JSON
-	response := map[string]string{
-		"id":      task.ID,
-		"date":    task.Date,
-		"title":   task.Title,
-		"comment": task.Comment,
-		"repeat":  task.Repeat,
+	// Добавляем чек-лист задачи
+	task.Subtasks, err = taskdb.GetSubtasks(id)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load subtasks"}`, http.StatusInternalServerError)
+		return
 	}
 
-	// Устанавливаем заголовок Content-Type и возвращаем ответ
+	// Устанавливаем заголовок Content-Type и возвращаем задачу в формате JSON
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(response)
+	w.Header().Set("ETag", taskETag(task.Version))
+	err = json.NewEncoder(w).Encode(task)
 	if err != nil {
 		return
 	}
@@ -202,13 +247,22 @@ func handleTaskDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, _, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	// Удаление задачи из базы данных
-	if err := taskdb.DeleteTaskFromDB(id); err != nil {
+	if err := taskdb.DeleteTaskFromDB(id, version); err != nil {
 		// Если задача не найдена, возвращаем ошибку 404
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, taskdb.ErrNotFound) {
 			http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, taskdb.ErrVersionMismatch) {
+			writeVersionMismatch(w)
+			return
+		}
 		// Если не удалось удалить задачу по другой причине, возвращаем ошибку
 		http.Error(w, `{"error": "Failed to delete task"}`, http.StatusInternalServerError)
 		return
@@ -226,38 +280,173 @@ func handleTaskDelete(w http.ResponseWriter, r *http.Request) {
 
 func HandleTasks(w http.ResponseWriter, r *http.Request) {
 	search := r.URL.Query().Get("search")
+	limitStr := r.URL.Query().Get("limit")
+	cursor := r.URL.Query().Get("cursor")
+
+	// Постраничный вывод включается параметрами limit или cursor
+	paged := limitStr != "" || cursor != ""
+	page := taskdb.Page{Cursor: cursor}
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > taskdb.MaxPageLimit {
+			http.Error(w, fmt.Sprintf(`{"error": "Limit must be between 1 and %d"}`, taskdb.MaxPageLimit), http.StatusBadRequest)
+			return
+		}
+		page.Limit = limit
+	}
 
-	var tasks []models.Task
-	var err error
-
+	query := taskdb.NewTaskQuery()
 	if search != "" {
 		// Проверка и форматирование даты
-		if parsedDate, err := time.Parse("02.01.2006", search); err == nil {
-			tasks, err = taskdb.SearchTasksByDate(parsedDate.Format("20060102"))
+		if parsedDate, parseErr := time.Parse("02.01.2006", search); parseErr == nil {
+			query.OnDate(parsedDate.Format("20060102"))
 		} else {
-			// Поиск по заголовку или комментарию
-			tasks, err = taskdb.SearchTasksByText(search)
+			// Поисковый запрос: слова, фразы, поля, исключения и OR
+			node, err := searchlang.Parse(search)
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error": "Invalid search query: %v"}`, err), http.StatusBadRequest)
+				return
+			}
+			query.Match(node)
 		}
-	} else {
-		// Возвращаем все задачи, если строка поиска пустая
-		tasks, err = taskdb.GetTasksFromDB()
 	}
 
+	if err := applyTaskFilters(r.URL.Query(), query); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": "%v"}`, err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := query.List(page)
+	if errors.Is(err, taskdb.ErrInvalidCursor) {
+		http.Error(w, `{"error": "Invalid cursor"}`, http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if tasks == nil {
-		tasks = []models.Task{}
+	// Без параметров страницы формат ответа остаётся прежним
+	var response any = map[string][]models.Task{"tasks": result.Tasks}
+	if paged {
+		response = map[string]any{
+			"tasks":       result.Tasks,
+			"next_cursor": result.NextCursor,
+			"total":       result.Total,
+		}
 	}
 
-	err = json.NewEncoder(w).Encode(map[string][]models.Task{"tasks": tasks})
+	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
 		return
 	}
 }
 
+// applyTaskFilters добавляет к запросу фильтры и сортировку из параметров списка задач
+func applyTaskFilters(params url.Values, query *taskdb.TaskQuery) error {
+	for _, name := range []string{"from", "to"} {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(dateutil.DateLayout, value); err != nil {
+			return fmt.Errorf("Invalid '%s' date format, expected YYYYMMDD", name)
+		}
+		if name == "from" {
+			query.DateFrom(value)
+		} else {
+			query.DateTo(value)
+		}
+	}
+
+	// Каждый параметр tag сужает выборку: задача должна иметь все метки
+	for _, tag := range params["tag"] {
+		if taskdb.NormalizeTag(tag) != "" {
+			query.Tag(tag)
+		}
+	}
+
+	// project=inbox — задачи без проекта
+	if value := params.Get("project"); value != "" {
+		if value == "inbox" {
+			query.Inbox()
+		} else {
+			projectID, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return errors.New("Invalid 'project' value, expected project ID or inbox")
+			}
+			query.Project(projectID)
+		}
+	}
+
+	// priority=1,2 — задачи с любым из перечисленных приоритетов
+	if value := params.Get("priority"); value != "" {
+		var levels []int
+		for _, part := range strings.Split(value, ",") {
+			level, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || level < 0 || level > taskdb.MaxPriority {
+				return errors.New("Invalid 'priority' value, expected levels from 0 to 4")
+			}
+			levels = append(levels, level)
+		}
+		query.Priority(levels...)
+	}
+
+	// ready=true — задачи без незавершённых блокирующих задач, ready=false — только заблокированные
+	if value := params.Get("ready"); value != "" {
+		ready, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("Invalid 'ready' value, expected true or false")
+		}
+		query.Ready(ready)
+	}
+
+	if value := params.Get("overdue"); value != "" {
+		overdue, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("Invalid 'overdue' value, expected true or false")
+		}
+		if overdue {
+			query.Overdue(time.Now().Format(dateutil.DateLayout))
+		}
+	}
+
+	if value := params.Get("recurring"); value != "" {
+		recurring, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("Invalid 'recurring' value, expected true or false")
+		}
+		query.Recurring(recurring)
+	}
+
+	if value := params.Get("repeat"); value != "" {
+		if !slices.Contains(taskdb.RepeatTypes, value) {
+			return errors.New("Invalid 'repeat' value, expected y, d, w or m")
+		}
+		query.RepeatType(value)
+	}
+
+	if value := params.Get("has_comment"); value != "" {
+		hasComment, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("Invalid 'has_comment' value, expected true or false")
+		}
+		query.HasComment(hasComment)
+	}
+
+	sort := params.Get("sort")
+	order := params.Get("order")
+	if sort != "" && !taskdb.ValidSortField(sort) {
+		return errors.New("Invalid 'sort' value, expected date, title, created or priority")
+	}
+	if order != "" && order != "asc" && order != "desc" {
+		return errors.New("Invalid 'order' value, expected asc or desc")
+	}
+	// Без sort сохраняется порядок по умолчанию: по дате или по релевантности
+	query.Sort(taskdb.SortField(sort), order == "desc")
+	return nil
+}
+
 func HandleTaskDone(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Query().Get("id")
 	if idStr == "" {
@@ -274,23 +463,53 @@ func HandleTaskDone(w http.ResponseWriter, r *http.Request) {
 
 	// Получаем задачу из базы данных
 	task, err := taskdb.GetTaskByID(id)
-	if err != nil {
+	if errors.Is(err, taskdb.ErrNotFound) {
 		http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load task"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Задачу с незавершёнными блокирующими задачами можно закрыть только с force=true
+	if len(task.BlockedBy) > 0 {
+		if force, _ := strconv.ParseBool(r.URL.Query().Get("force")); !force {
+			writeBlocked(w, task.BlockedBy)
+			return
+		}
+	}
+
+	// Клиент может передать дату, которую он видел; иначе сверяемся с прочитанной
+	date := task.Date
+	if expected := r.URL.Query().Get("date"); expected != "" {
+		if _, err := time.Parse("20060102", expected); err != nil {
+			http.Error(w, `{"error": "Invalid date format, expected YYYYMMDD"}`, http.StatusBadRequest)
+			return
+		}
+		date = expected
+	}
 
 	// Рассчитываем следующую дату, если задача повторяющаяся
 	var nextDate string
 	if task.Repeat != "" {
-		nextDate, err = dateutil.NextDate(time.Now(), task.Date, task.Repeat)
+		nextDate, err = dateutil.NextDate(time.Now(), date, task.Repeat)
 		if err != nil {
 			http.Error(w, `{"error": "Failed to calculate next date"}`, http.StatusBadRequest)
 			return
 		}
 	}
 
-	// Отмечаем задачу как выполненную
-	if err := taskdb.MarkTaskAsDone(id, nextDate); err != nil {
+	// Отмечаем задачу как выполненную, только если её дата ещё не сдвинулась
+	if err := taskdb.MarkTaskAsDone(id, date, nextDate); err != nil {
+		if errors.Is(err, taskdb.ErrAlreadyDone) {
+			http.Error(w, `{"error": "Task has already been completed"}`, http.StatusConflict)
+			return
+		}
+		if errors.Is(err, taskdb.ErrNotFound) {
+			http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+			return
+		}
 		http.Error(w, `{"error": "Failed to mark task as done"}`, http.StatusInternalServerError)
 		return
 	}
@@ -333,3 +552,37 @@ func HandleNextDate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// writeEmptyJSON отвечает пустым JSON-объектом, как при успешном удалении задачи
+func writeEmptyJSON(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("{}"))
+	if err != nil {
+		return
+	}
+}
+
+// parseExistingTaskID разбирает идентификатор задачи и проверяет, что она существует
+func parseExistingTaskID(w http.ResponseWriter, idStr string) (int, bool) {
+	if idStr == "" {
+		http.Error(w, `{"error": "Task ID is required"}`, http.StatusBadRequest)
+		return 0, false
+	}
+	taskID, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID format"}`, http.StatusBadRequest)
+		return 0, false
+	}
+
+	_, err = taskdb.GetTaskByID(taskID)
+	if errors.Is(err, taskdb.ErrNotFound) {
+		http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+		return 0, false
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load task"}`, http.StatusInternalServerError)
+		return 0, false
+	}
+	return taskID, true
+}
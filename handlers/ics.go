@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/deeramster/go_final_project/ical"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+// HandleImportICS загружает события и задачи из файла iCalendar (POST, тело запроса или поле file).
+// Принимает общие параметры импорта; при dry_run=true в ответе есть preview — задачи,
+// в которые превратятся записи календаря. Нечитаемые записи попадают в errors, пропущенные
+// выполненные и отменённые записи и потери при преобразовании (время, непредставимые RRULE,
+// напоминания) — в warnings.
+func HandleImportICS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, ok := parseImportOptions(w, r)
+	if !ok {
+		return
+	}
+	body, ok := importBody(w, r)
+	if !ok {
+		return
+	}
+
+	doc, lines, entryErrors, warnings, err := ical.ReadTasks(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, `{"error": "Import file is too large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, "Invalid iCalendar: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var issues []taskdb.ImportIssue
+	for _, e := range entryErrors {
+		issues = append(issues, taskdb.ImportIssue{Index: -1, Row: e.Line, Message: e.Message})
+	}
+	result, err := importWithIssues(doc, lines, issues, opts)
+	for _, e := range warnings {
+		result.Warnings = append(result.Warnings, taskdb.ImportIssue{Index: -1, Row: e.Line, Message: e.Message})
+	}
+
+	var preview []models.ExportTask
+	if opts.DryRun {
+		preview = doc.Tasks
+	}
+	writeImportPreview(w, result, preview, err)
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+// colorPattern допускает цвет проекта в виде #RRGGBB
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type moveTasksRequest struct {
+	TaskIDs   []string `json:"task_ids"`
+	ProjectID *int64   `json:"project_id"`
+}
+
+// HandleProjects обслуживает CRUD для проектов (списков задач)
+func HandleProjects(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleProjectsGet(w, r)
+	case http.MethodPost:
+		handleProjectPost(w, r)
+	case http.MethodPut:
+		handleProjectPut(w, r)
+	case http.MethodDelete:
+		handleProjectDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func handleProjectsGet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Без id возвращаем все проекты
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		handleProjectsList(w)
+		return
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID format"}`, http.StatusBadRequest)
+		return
+	}
+
+	project, err := taskdb.GetProjectByID(id)
+	if errors.Is(err, taskdb.ErrProjectNotFound) {
+		http.Error(w, `{"error": "Project not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load project"}`, http.StatusInternalServerError)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(project)
+	if err != nil {
+		return
+	}
+}
+
+func handleProjectsList(w http.ResponseWriter) {
+	projects, err := taskdb.GetProjects()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load projects"}`, http.StatusInternalServerError)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(map[string][]models.Project{"projects": projects})
+	if err != nil {
+		return
+	}
+}
+
+func handleProjectPost(w http.ResponseWriter, r *http.Request) {
+	var project models.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if !validateProject(w, &project) {
+		return
+	}
+
+	id, err := taskdb.AddProject(project)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to add project"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int64{"id": id})
+	if err != nil {
+		return
+	}
+}
+
+func handleProjectPut(w http.ResponseWriter, r *http.Request) {
+	var project models.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if project.ID == 0 {
+		http.Error(w, `{"error": "Project ID is required"}`, http.StatusBadRequest)
+		return
+	}
+	if !validateProject(w, &project) {
+		return
+	}
+
+	err := taskdb.UpdateProject(project)
+	if errors.Is(err, taskdb.ErrProjectNotFound) {
+		http.Error(w, `{"error": "Project not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to update project"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(project)
+	if err != nil {
+		return
+	}
+}
+
+func handleProjectDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// cascade=true удаляет задачи проекта, иначе они переносятся во «Входящие»
+	cascade := false
+	if value := r.URL.Query().Get("cascade"); value != "" {
+		cascade, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, `{"error": "Invalid 'cascade' value, expected true or false"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = taskdb.DeleteProject(id, cascade)
+	if errors.Is(err, taskdb.ErrProjectNotFound) {
+		http.Error(w, `{"error": "Project not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to delete project"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeEmptyJSON(w)
+}
+
+// HandleProjectMove переносит задачи в проект или во «Входящие», если project_id равен null
+func HandleProjectMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req moveTasksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.TaskIDs) == 0 {
+		http.Error(w, `{"error": "Task IDs are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]int64, 0, len(req.TaskIDs))
+	for _, idStr := range req.TaskIDs {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, `{"error": "Invalid ID format"}`, http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	moved, err := taskdb.MoveTasks(ids, req.ProjectID)
+	if errors.Is(err, taskdb.ErrProjectNotFound) {
+		http.Error(w, `{"error": "Project not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to move tasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int64{"moved": moved})
+	if err != nil {
+		return
+	}
+}
+
+// validateProject проверяет имя и цвет проекта и пишет ошибку в ответ
+func validateProject(w http.ResponseWriter, project *models.Project) bool {
+	project.Name = strings.TrimSpace(project.Name)
+	if project.Name == "" {
+		http.Error(w, `{"error": "Project name is required"}`, http.StatusBadRequest)
+		return false
+	}
+	if project.Color != "" && !colorPattern.MatchString(project.Color) {
+		http.Error(w, `{"error": "Invalid color, expected #RRGGBB"}`, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// checkTaskProject проверяет, что проект задачи существует, и пишет ошибку в ответ
+func checkTaskProject(w http.ResponseWriter, task models.Task) bool {
+	if task.ProjectID == nil {
+		return true
+	}
+	exists, err := taskdb.ProjectExists(*task.ProjectID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to check project"}`, http.StatusInternalServerError)
+		return false
+	}
+	if !exists {
+		http.Error(w, `{"error": "Project not found"}`, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
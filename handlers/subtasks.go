@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+type subtaskRequest struct {
+	TaskID string `json:"task_id"`
+	Title  string `json:"title"`
+}
+
+type subtaskOrderRequest struct {
+	TaskID string  `json:"task_id"`
+	Order  []int64 `json:"order"`
+}
+
+// HandleSubtasks обслуживает чек-лист задачи: список, добавление и удаление пунктов
+func HandleSubtasks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleSubtasksGet(w, r)
+	case http.MethodPost:
+		handleSubtaskPost(w, r)
+	case http.MethodDelete:
+		handleSubtaskDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func handleSubtasksGet(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := parseExistingTaskID(w, r.URL.Query().Get("task_id"))
+	if !ok {
+		return
+	}
+
+	subtasks, err := taskdb.GetSubtasks(taskID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load subtasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string][]models.Subtask{"subtasks": subtasks})
+	if err != nil {
+		return
+	}
+}
+
+func handleSubtaskPost(w http.ResponseWriter, r *http.Request) {
+	var req subtaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		http.Error(w, `{"error": "Title is required"}`, http.StatusBadRequest)
+		return
+	}
+	taskID, ok := parseExistingTaskID(w, req.TaskID)
+	if !ok {
+		return
+	}
+
+	id, err := taskdb.AddSubtask(taskID, req.Title)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to add subtask"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]int64{"id": id})
+	if err != nil {
+		return
+	}
+}
+
+func handleSubtaskDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	err = taskdb.DeleteSubtask(id)
+	if errors.Is(err, taskdb.ErrSubtaskNotFound) {
+		http.Error(w, `{"error": "Subtask not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to delete subtask"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeEmptyJSON(w)
+}
+
+// HandleSubtaskToggle отмечает пункт чек-листа выполненным или снимает отметку
+func HandleSubtaskToggle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	subtask, err := taskdb.ToggleSubtask(id)
+	if errors.Is(err, taskdb.ErrSubtaskNotFound) {
+		http.Error(w, `{"error": "Subtask not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to toggle subtask"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(subtask)
+	if err != nil {
+		return
+	}
+}
+
+// HandleSubtaskReorder задаёт новый порядок пунктов чек-листа
+func HandleSubtaskReorder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req subtaskOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	taskID, ok := parseExistingTaskID(w, req.TaskID)
+	if !ok {
+		return
+	}
+
+	err := taskdb.ReorderSubtasks(taskID, req.Order)
+	if errors.Is(err, taskdb.ErrInvalidOrder) {
+		http.Error(w, `{"error": "Order must list every subtask of the task once"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to reorder subtasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeEmptyJSON(w)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/deeramster/go_final_project/taskdb"
+)
+
+type tagRenameRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type tagMergeRequest struct {
+	From []string `json:"from"`
+	To   string   `json:"to"`
+}
+
+// HandleTags возвращает список меток с количеством задач
+func HandleTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	tags, err := taskdb.GetTags()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to load tags"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(map[string]any{"tags": tags})
+	if err != nil {
+		return
+	}
+}
+
+// HandleTagRename переименовывает метку у всех задач
+func HandleTagRename(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req tagRenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if taskdb.NormalizeTag(req.From) == "" || taskdb.NormalizeTag(req.To) == "" {
+		http.Error(w, `{"error": "Both 'from' and 'to' tags are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	err := taskdb.RenameTag(req.From, req.To)
+	switch {
+	case errors.Is(err, taskdb.ErrTagNotFound):
+		http.Error(w, `{"error": "Tag not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, taskdb.ErrTagExists):
+		// Переименование в существующую метку — это слияние
+		http.Error(w, `{"error": "Tag already exists, use /api/tags/merge"}`, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, `{"error": "Failed to rename tag"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeEmptyJSON(w)
+}
+
+// HandleTagMerge переносит задачи с нескольких меток на одну и удаляет исходные
+func HandleTagMerge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req tagMergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.From) == 0 || taskdb.NormalizeTag(req.To) == "" {
+		http.Error(w, `{"error": "Both 'from' and 'to' tags are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	err := taskdb.MergeTags(req.From, req.To)
+	switch {
+	case errors.Is(err, taskdb.ErrTagNotFound):
+		http.Error(w, `{"error": "Tag not found"}`, http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, `{"error": "Failed to merge tags"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeEmptyJSON(w)
+}
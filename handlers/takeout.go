@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/googletasks"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+	"github.com/deeramster/go_final_project/todoist"
+)
+
+// HandleImportGoogleTasks загружает задачи из файла Tasks.json выгрузки Google Takeout
+// (POST, тело запроса или поле file). Списки задач становятся проектами, подзадачи — чек-листом.
+// Принимает общие параметры импорта; при dry_run=true в ответе есть preview.
+// Выполненные и удалённые задачи пропускаются и вместе с потерями при преобразовании
+// перечисляются в warnings, в errors попадают только задачи, которые не удалось прочитать.
+func HandleImportGoogleTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, ok := parseImportOptions(w, r)
+	if !ok {
+		return
+	}
+	body, ok := importBody(w, r)
+	if !ok {
+		return
+	}
+
+	doc, taskErrors, warnings, err := googletasks.Read(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, `{"error": "Import file is too large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, "Invalid Google Tasks export: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var issues []taskdb.ImportIssue
+	for _, e := range taskErrors {
+		issues = append(issues, taskdb.ImportIssue{Index: -1, Title: e.Title, Message: e.Message})
+	}
+	result, err := importWithIssues(doc, nil, issues, opts)
+	for _, e := range warnings {
+		result.Warnings = append(result.Warnings, taskdb.ImportIssue{Index: -1, Title: e.Title, Message: e.Message})
+	}
+
+	var preview []models.ExportTask
+	if opts.DryRun {
+		preview = doc.Tasks
+	}
+	writeImportPreview(w, result, preview, err)
+}
+
+// HandleImportTodoist загружает задачи из CSV-выгрузки проекта Todoist (POST, тело запроса или поле file).
+// В Todoist каждый проект выгружается отдельным файлом, параметр project задаёт имя проекта
+// для его задач. Разделы становятся метками, подзадачи — чек-листом, а правила повторения
+// на английском ("every mon, fri", "every 15th") переводятся в наши, если их можно так записать.
+// Принимает общие параметры импорта; потери при преобразовании перечисляются в warnings.
+func HandleImportTodoist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, ok := parseImportOptions(w, r)
+	if !ok {
+		return
+	}
+	body, ok := importBody(w, r)
+	if !ok {
+		return
+	}
+
+	project := strings.TrimSpace(r.URL.Query().Get("project"))
+	doc, lines, rowErrors, warnings, err := todoist.Read(body, project, time.Now())
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, `{"error": "Import file is too large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, "Invalid Todoist export: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var issues []taskdb.ImportIssue
+	for _, e := range rowErrors {
+		issues = append(issues, taskdb.ImportIssue{Index: -1, Row: e.Line, Message: e.Message})
+	}
+	result, err := importWithIssues(doc, lines, issues, opts)
+	for _, e := range warnings {
+		result.Warnings = append(result.Warnings, taskdb.ImportIssue{Index: -1, Row: e.Line, Message: e.Message})
+	}
+
+	var preview []models.ExportTask
+	if opts.DryRun {
+		preview = doc.Tasks
+	}
+	writeImportPreview(w, result, preview, err)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/deeramster/go_final_project/taskdb"
+	"github.com/deeramster/go_final_project/todotxt"
+)
+
+// HandleExportTodoTxt выгружает задачи в формате todo.txt (GET)
+func HandleExportTodoTxt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	doc, err := taskdb.ExportTasks()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to export tasks"}`, http.StatusInternalServerError)
+		return
+	}
+
+	name := "todo-" + time.Now().Format("20060102") + ".txt"
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	err = todotxt.Write(w, doc)
+	if err != nil {
+		return
+	}
+}
+
+// HandleImportTodoTxt загружает задачи из файла todo.txt (POST, тело запроса или поле file).
+// Принимает общие параметры импорта. Строки с ошибками перечисляются в errors, пропущенные
+// выполненные задачи (x ...) и потери при преобразовании (например, неподдерживаемый rec:) — в warnings.
+func HandleImportTodoTxt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	opts, ok := parseImportOptions(w, r)
+	if !ok {
+		return
+	}
+	body, ok := importBody(w, r)
+	if !ok {
+		return
+	}
+
+	doc, lines, lineErrors, warnings, err := todotxt.Read(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, `{"error": "Import file is too large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, "Invalid todo.txt: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var issues []taskdb.ImportIssue
+	for _, e := range lineErrors {
+		issues = append(issues, taskdb.ImportIssue{Index: -1, Row: e.Line, Message: e.Message})
+	}
+	result, err := importWithIssues(doc, lines, issues, opts)
+	for _, e := range warnings {
+		result.Warnings = append(result.Warnings, taskdb.ImportIssue{Index: -1, Row: e.Line, Message: e.Message})
+	}
+	writeImportResult(w, result, err)
+}
@@ -0,0 +1,206 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNoCalendar is returned when the input has no VCALENDAR object
+var ErrNoCalendar = errors.New("no VCALENDAR in the input")
+
+// Get returns the first property with the name
+func (c *Component) Get(name string) (Property, bool) {
+	for _, p := range c.Properties {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// All returns every property with the name, such as repeated CATEGORIES
+func (c *Component) All(name string) []Property {
+	var props []Property
+	for _, p := range c.Properties {
+		if p.Name == name {
+			props = append(props, p)
+		}
+	}
+	return props
+}
+
+// Param returns the value of a parameter, or "" when it isn't set
+func (p Property) Param(name string) string {
+	for _, param := range p.Params {
+		if param.Name == name {
+			return param.Value
+		}
+	}
+	return ""
+}
+
+// Decode reads the first VCALENDAR object of the input. Folded lines are
+// joined, LF line endings are accepted along with CRLF, and names are
+// upper-cased. Line of each component is where its BEGIN line starts.
+func Decode(r io.Reader) (*Component, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+
+	var stack []*Component
+	var root *Component
+	var logical string
+	var start, n int
+
+	// handle processes one unfolded content line
+	handle := func(line string, lineNo int) error {
+		if strings.TrimSpace(line) == "" {
+			return nil
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		switch p.Name {
+		case "BEGIN":
+			c := &Component{Name: strings.ToUpper(p.Value), Line: lineNo}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			} else if root != nil {
+				// Everything after the first calendar is ignored
+				return io.EOF
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return fmt.Errorf("line %d: unexpected END:%s", lineNo, p.Value)
+			}
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				root = c
+			}
+		default:
+			if len(stack) == 0 {
+				return fmt.Errorf("line %d: property outside of a component", lineNo)
+			}
+			c := stack[len(stack)-1]
+			c.Properties = append(c.Properties, p)
+		}
+		return nil
+	}
+
+	for sc.Scan() {
+		n++
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			logical += line[1:]
+			continue
+		}
+		if start > 0 {
+			if err := handle(logical, start); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		logical, start = line, n
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if start > 0 && root == nil {
+		if err := handle(logical, start); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("line %d: %s is not closed", stack[len(stack)-1].Line, stack[len(stack)-1].Name)
+	}
+	if root == nil || root.Name != "VCALENDAR" {
+		return nil, ErrNoCalendar
+	}
+	return root, nil
+}
+
+// parseLine splits a content line into name, parameters and value;
+// colons and semicolons inside quoted parameter values don't count
+func parseLine(line string) (Property, error) {
+	var p Property
+	var quoted bool
+	var parts []string
+	begin := 0
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == ';' || c == ':':
+			parts = append(parts, line[begin:i])
+			begin = i + 1
+			if c == ':' {
+				p.Value = line[begin:]
+				return finishProperty(p, parts)
+			}
+		}
+	}
+	return p, errors.New("missing ':' in content line")
+}
+
+func finishProperty(p Property, parts []string) (Property, error) {
+	p.Name = strings.ToUpper(parts[0])
+	if p.Name == "" {
+		return p, errors.New("empty property name")
+	}
+	for _, part := range parts[1:] {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return p, fmt.Errorf("invalid parameter %q", part)
+		}
+		p.Params = append(p.Params, Param{Name: strings.ToUpper(name), Value: strings.ReplaceAll(value, `"`, "")})
+	}
+	return p, nil
+}
+
+// Unescape reverses Escape for a TEXT value
+func Unescape(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			i++
+			switch text[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(text[i])
+			}
+			continue
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
+
+// SplitText splits a list of TEXT values at unescaped commas and unescapes them
+func SplitText(list string) []string {
+	var values []string
+	begin := 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '\\':
+			i++
+		case ',':
+			values = append(values, Unescape(list[begin:i]))
+			begin = i + 1
+		}
+	}
+	return append(values, Unescape(list[begin:]))
+}
@@ -0,0 +1,115 @@
+// Package ical reads and writes iCalendar (RFC 5545) data for calendar clients.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineLength is the longest content line in octets before it is folded
+const maxLineLength = 75
+
+// Component is a calendar object such as VCALENDAR, VEVENT or VTODO
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []*Component
+	// Line is where the component begins in decoded input
+	Line int
+}
+
+// Property is a content line; Value is written as is, so text values
+// must be escaped with Escape first
+type Property struct {
+	Name   string
+	Params []Param
+	Value  string
+}
+
+// Param is a property parameter such as VALUE=DATE
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Add appends a property with a raw value
+func (c *Component) Add(name, value string, params ...Param) {
+	c.Properties = append(c.Properties, Property{Name: name, Params: params, Value: value})
+}
+
+// Set replaces the value of the first property with the name, or appends one
+func (c *Component) Set(name, value string) {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			c.Properties[i].Value = value
+			return
+		}
+	}
+	c.Add(name, value)
+}
+
+// AddText appends a property with a text value, escaping it
+func (c *Component) AddText(name, text string) {
+	c.Add(name, Escape(text))
+}
+
+// Escape escapes a TEXT value: backslashes, semicolons, commas and line breaks
+func Escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch r {
+		case '\\', ';', ',':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Encode writes the component with CRLF line endings and long lines folded
+func Encode(w io.Writer, c *Component) error {
+	bw := bufio.NewWriter(w)
+	writeComponent(bw, c)
+	return bw.Flush()
+}
+
+func writeComponent(w *bufio.Writer, c *Component) {
+	writeLine(w, "BEGIN:"+c.Name)
+	for _, p := range c.Properties {
+		var line strings.Builder
+		line.WriteString(p.Name)
+		for _, param := range p.Params {
+			line.WriteString(";" + param.Name + "=" + param.Value)
+		}
+		line.WriteString(":" + p.Value)
+		writeLine(w, line.String())
+	}
+	for _, child := range c.Components {
+		writeComponent(w, child)
+	}
+	writeLine(w, "END:"+c.Name)
+}
+
+// writeLine folds a content line into lines of at most 75 octets,
+// never splitting a UTF-8 character; continuation lines start with a space
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the next line
+		limit = maxLineLength - 1
+	}
+	w.WriteString(line + "\r\n")
+}
@@ -0,0 +1,369 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/deeramster/go_final_project/models"
+)
+
+// Issue describes a calendar entry that could not be read or was read with losses
+type Issue struct {
+	Line    int
+	Message string
+}
+
+// ErrSkipped is wrapped by the errors of TaskFromComponent for entries that
+// are well-formed but have no place among open tasks
+var ErrSkipped = errors.New("skipped")
+
+// weekdayNumbers maps BYDAY codes to the day numbers of the "w" rule
+var weekdayNumbers = map[string]int{"MO": 1, "TU": 2, "WE": 3, "TH": 4, "FR": 5, "SA": 6, "SU": 7}
+
+// ReadTasks converts the VEVENT and VTODO entries of a calendar to an export
+// document ready for import. Completed and cancelled entries and changed
+// occurrences are skipped with a warning, unreadable entries are reported in
+// errs and left out, losses go to warnings. lines is the line where each
+// returned task begins.
+func ReadTasks(r io.Reader) (doc models.Export, lines []int, errs, warnings []Issue, err error) {
+	doc = models.Export{Format: models.ExportFormat, Version: models.ExportVersion, Projects: []models.ExportProject{}, Tasks: []models.ExportTask{}}
+
+	calendar, err := Decode(r)
+	if err != nil {
+		return doc, nil, nil, nil, err
+	}
+
+	now := time.Now()
+	for _, c := range calendar.Components {
+		if c.Name != KindEvent && c.Name != KindTodo {
+			continue
+		}
+
+		t, lost, err := TaskFromComponent(c, now)
+		if errors.Is(err, ErrSkipped) {
+			warnings = append(warnings, Issue{Line: c.Line, Message: err.Error()})
+			continue
+		}
+		if err != nil {
+			errs = append(errs, Issue{Line: c.Line, Message: err.Error()})
+			continue
+		}
+		// A recurring entry that started in the past continues from its next occurrence, as new tasks do
+		if t.Repeat != "" && t.Date < now.Format(dateutil.DateLayout) {
+			if next, err := dateutil.NextDate(now, t.Date, t.Repeat); err == nil {
+				lost = append(lost, fmt.Sprintf("start date %s moved to the next occurrence", t.Date))
+				t.Date = next
+			}
+		}
+		for _, msg := range lost {
+			warnings = append(warnings, Issue{Line: c.Line, Message: msg})
+		}
+		t.ID = strconv.Itoa(c.Line)
+		doc.Tasks = append(doc.Tasks, t)
+		lines = append(lines, c.Line)
+	}
+	return doc, lines, errs, warnings, nil
+}
+
+// TaskFromComponent converts a VEVENT or VTODO to a task. SUMMARY becomes the
+// title, DESCRIPTION the comment, DUE or DTSTART the date (now when there is
+// none) and RRULE the repeat rule; lost lists what had no place in the task.
+// Completed and cancelled entries and changed occurrences of recurring ones
+// return an error wrapping ErrSkipped.
+func TaskFromComponent(c *Component, now time.Time) (t models.ExportTask, lost []string, err error) {
+	if _, ok := c.Get("RECURRENCE-ID"); ok {
+		return t, nil, fmt.Errorf("changed occurrence of a recurring entry %w", ErrSkipped)
+	}
+	status, _ := c.Get("STATUS")
+	switch strings.ToUpper(status.Value) {
+	case "COMPLETED":
+		return t, nil, fmt.Errorf("completed task %w", ErrSkipped)
+	case "CANCELLED":
+		return t, nil, fmt.Errorf("cancelled entry %w", ErrSkipped)
+	}
+	if _, ok := c.Get("COMPLETED"); ok {
+		return t, nil, fmt.Errorf("completed task %w", ErrSkipped)
+	}
+
+	if p, ok := c.Get("SUMMARY"); ok {
+		t.Title = strings.TrimSpace(Unescape(p.Value))
+	}
+	if p, ok := c.Get("DESCRIPTION"); ok {
+		t.Comment = strings.TrimSpace(Unescape(p.Value))
+	}
+
+	date := now
+	var hasTime bool
+	start, ok := c.Get("DTSTART")
+	if due, hasDue := c.Get("DUE"); hasDue && c.Name == KindTodo {
+		start, ok = due, true
+	}
+	if ok {
+		date, hasTime, err = parseDate(start)
+		if err != nil {
+			return t, nil, err
+		}
+		if hasTime {
+			lost = append(lost, fmt.Sprintf("time of day dropped from %s:%s", start.Name, start.Value))
+		}
+	}
+	t.Date = date.Format(dateutil.DateLayout)
+
+	if end, ok := c.Get("DTEND"); ok && c.Name == KindEvent {
+		if endDate, endHasTime, err := parseDate(end); err == nil {
+			last := endDate
+			if !endHasTime {
+				// The end date of an all-day event is exclusive
+				last = endDate.AddDate(0, 0, -1)
+			}
+			if last.Format(dateutil.DateLayout) > t.Date {
+				lost = append(lost, fmt.Sprintf("end date %s dropped", last.Format(dateutil.DateLayout)))
+			}
+		}
+	}
+
+	if p, ok := c.Get("RRULE"); ok {
+		var dropped []string
+		t.Repeat, dropped = RepeatFromRRule(p.Value, date)
+		lost = append(lost, dropped...)
+	}
+	for _, name := range []string{"RDATE", "EXDATE", "LOCATION", "URL", "ATTACH"} {
+		if _, ok := c.Get(name); ok {
+			lost = append(lost, name+" dropped")
+		}
+	}
+	for _, child := range c.Components {
+		if child.Name == "VALARM" {
+			lost = append(lost, "reminders dropped")
+			break
+		}
+	}
+
+	if p, ok := c.Get("PRIORITY"); ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(p.Value)); err == nil {
+			t.Priority = priorityFromICal(n)
+		}
+	}
+	for _, p := range c.All("CATEGORIES") {
+		for _, tag := range SplitText(p.Value) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				t.Tags = append(t.Tags, tag)
+			}
+		}
+	}
+	return t, lost, nil
+}
+
+// parseDate reads a DATE or DATE-TIME value as a local date; hasTime reports
+// whether a time of day was given. UTC times and times with a known TZID are
+// converted to local time first, floating times are taken as they are.
+func parseDate(p Property) (date time.Time, hasTime bool, err error) {
+	value := strings.TrimSpace(p.Value)
+	if p.Param("VALUE") == "DATE" || len(value) == len(dateutil.DateLayout) {
+		date, err = time.ParseInLocation(dateutil.DateLayout, value, time.Local)
+		if err != nil {
+			return date, false, fmt.Errorf("invalid %s %q", p.Name, value)
+		}
+		return date, false, nil
+	}
+
+	loc := time.Local
+	if tzid := p.Param("TZID"); tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	if strings.HasSuffix(value, "Z") {
+		date, err = time.Parse(stampLayout, value)
+	} else {
+		date, err = time.ParseInLocation("20060102T150405", value, loc)
+	}
+	if err != nil {
+		return date, false, fmt.Errorf("invalid %s %q", p.Name, value)
+	}
+	return date.In(time.Local), true, nil
+}
+
+// priorityFromICal maps an iCalendar priority (1 highest, 9 lowest, 0 none) to a task priority
+func priorityFromICal(p int) int {
+	switch {
+	case p >= 1 && p <= 2:
+		return 1
+	case p >= 3 && p <= 4:
+		return 2
+	case p == 5:
+		return 3
+	case p >= 6 && p <= 9:
+		return 4
+	}
+	return 0
+}
+
+// RepeatFromRRule translates an RRULE value to a repeat rule for a task
+// starting on date; lost lists the parts that had to be dropped. When the
+// recurrence can't be represented at all the rule is empty.
+func RepeatFromRRule(rrule string, date time.Time) (repeat string, lost []string) {
+	unsupported := []string{fmt.Sprintf("recurrence RRULE:%s can't be represented and was dropped", rrule)}
+
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rrule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", unsupported
+		}
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+
+	interval := 1
+	if s, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return "", unsupported
+		}
+		interval = n
+	}
+	for _, key := range []string{"COUNT", "UNTIL"} {
+		if s, ok := parts[key]; ok {
+			lost = append(lost, fmt.Sprintf("end of recurrence %s=%s dropped", key, s))
+		}
+	}
+	for key := range parts {
+		switch key {
+		case "FREQ", "INTERVAL", "COUNT", "UNTIL", "WKST", "BYDAY", "BYMONTHDAY", "BYMONTH":
+		default:
+			return "", unsupported
+		}
+	}
+
+	days, ok := byDay(parts["BYDAY"])
+	if !ok {
+		return "", unsupported
+	}
+	monthDays, ok := numberList(parts["BYMONTHDAY"], func(n int) bool { return (n >= 1 && n <= 31) || n == -1 || n == -2 })
+	if !ok {
+		return "", unsupported
+	}
+	months, ok := numberList(parts["BYMONTH"], func(n int) bool { return n >= 1 && n <= 12 })
+	if !ok {
+		return "", unsupported
+	}
+
+	switch parts["FREQ"] {
+	case "DAILY":
+		switch {
+		case monthDays != "" || months != "":
+		case days != "" && interval == 1:
+			// Daily on some weekdays, e.g. on working days
+			return "w " + days, lost
+		case days == "" && interval <= 400:
+			return "d " + strconv.Itoa(interval), lost
+		}
+
+	case "WEEKLY":
+		weekday := strconv.Itoa(isoWeekday(date))
+		switch {
+		case monthDays != "" || months != "":
+		case interval == 1 && days == "":
+			return "w " + weekday, lost
+		case interval == 1:
+			return "w " + days, lost
+		case (days == "" || days == weekday) && interval*7 <= 400:
+			return "d " + strconv.Itoa(interval*7), lost
+		}
+
+	case "MONTHLY":
+		if days != "" {
+			break
+		}
+		if monthDays == "" {
+			monthDays = strconv.Itoa(date.Day())
+		}
+		switch {
+		case interval == 1 && months == "":
+			return "m " + monthDays, lost
+		case interval == 1:
+			return "m " + monthDays + " " + months, lost
+		case months == "" && 12%interval == 0:
+			// Every few months is the same months of every year
+			var list []int
+			for m := int(date.Month()); len(list) < 12/interval; m += interval {
+				list = append(list, (m-1)%12+1)
+			}
+			slices.Sort(list)
+			return "m " + monthDays + " " + joinNumbers(list), lost
+		}
+
+	case "YEARLY":
+		if days != "" || interval != 1 {
+			break
+		}
+		day, month := strconv.Itoa(date.Day()), strconv.Itoa(int(date.Month()))
+		if (monthDays == "" || monthDays == day) && (months == "" || months == month) {
+			return "y", lost
+		}
+		if monthDays == "" {
+			monthDays = day
+		}
+		if months == "" {
+			months = month
+		}
+		return "m " + monthDays + " " + months, lost
+	}
+	return "", unsupported
+}
+
+// byDay converts a BYDAY list to "w" rule days; days with an ordinal,
+// such as 2MO for the second Monday, can't be represented
+func byDay(list string) (string, bool) {
+	if list == "" {
+		return "", true
+	}
+	var days []int
+	for _, code := range strings.Split(list, ",") {
+		n, ok := weekdayNumbers[code]
+		if !ok {
+			return "", false
+		}
+		days = append(days, n)
+	}
+	slices.Sort(days)
+	return joinNumbers(days), true
+}
+
+// numberList checks a comma-separated list of numbers
+func numberList(list string, valid func(int) bool) (string, bool) {
+	if list == "" {
+		return "", true
+	}
+	var numbers []int
+	for _, s := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil || !valid(n) {
+			return "", false
+		}
+		numbers = append(numbers, n)
+	}
+	return joinNumbers(numbers), true
+}
+
+func joinNumbers(numbers []int) string {
+	list := make([]string, len(numbers))
+	for i, n := range numbers {
+		list[i] = strconv.Itoa(n)
+	}
+	return strings.Join(list, ",")
+}
+
+// isoWeekday numbers weekdays from 1 for Monday to 7 for Sunday, as the "w" rule does
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
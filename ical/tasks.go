@@ -0,0 +1,115 @@
+package ical
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/deeramster/go_final_project/models"
+)
+
+// Components a task can be written as: an all-day event or a to-do
+const (
+	KindEvent = "VEVENT"
+	KindTodo  = "VTODO"
+)
+
+// ProductID identifies this application in PRODID
+const ProductID = "-//go_final_project//Scheduler//RU"
+
+// stampLayout is the UTC date-time format of DTSTAMP
+const stampLayout = "20060102T150405Z"
+
+// weekdays are the BYDAY codes for Monday (1) to Sunday (7) of the "w" rule
+var weekdays = []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// priorities maps task priorities to iCalendar ones, where 1 is the highest and 9 the lowest
+var priorities = map[int]int{1: 1, 2: 3, 3: 5, 4: 7}
+
+// UID returns the unique id of a task's calendar entry; it stays the same
+// while the task exists, so clients update entries instead of duplicating them
+func UID(taskID string) string {
+	return "task-" + taskID + "@go_final_project"
+}
+
+// Calendar returns an empty VCALENDAR with a display name
+func Calendar(name string) *Component {
+	c := &Component{Name: "VCALENDAR"}
+	c.Add("VERSION", "2.0")
+	c.Add("PRODID", ProductID)
+	c.Add("CALSCALE", "GREGORIAN")
+	c.AddText("X-WR-CALNAME", name)
+	return c
+}
+
+// TaskComponent converts a task to a VEVENT or VTODO stamped at stamp
+func TaskComponent(t models.Task, kind string, stamp time.Time) *Component {
+	c := &Component{Name: kind}
+	c.Add("UID", UID(t.ID))
+	c.Add("DTSTAMP", stamp.UTC().Format(stampLayout))
+	if t.Version > 1 {
+		c.Add("SEQUENCE", strconv.FormatInt(t.Version-1, 10))
+	}
+
+	date := Param{Name: "VALUE", Value: "DATE"}
+	c.Add("DTSTART", t.Date, date)
+	if kind == KindTodo {
+		c.Add("DUE", t.Date, date)
+		c.Add("STATUS", "NEEDS-ACTION")
+	}
+	if rule, ok := RRule(t.Repeat); ok {
+		c.Add("RRULE", rule)
+	}
+
+	c.AddText("SUMMARY", t.Title)
+	if t.Comment != "" {
+		c.AddText("DESCRIPTION", t.Comment)
+	}
+	if t.Priority != nil {
+		if p, ok := priorities[*t.Priority]; ok {
+			c.Add("PRIORITY", strconv.Itoa(p))
+		}
+	}
+	if len(t.Tags) > 0 {
+		categories := make([]string, len(t.Tags))
+		for i, tag := range t.Tags {
+			categories[i] = Escape(tag)
+		}
+		c.Add("CATEGORIES", strings.Join(categories, ","))
+	}
+	return c
+}
+
+// RRule translates a repeat rule to an RRULE value; ok is false when the
+// task doesn't repeat or the rule is invalid
+func RRule(repeat string) (string, bool) {
+	if repeat == "" || dateutil.ValidateRepeat(repeat) != nil {
+		return "", false
+	}
+
+	parts := strings.Split(repeat, " ")
+	switch parts[0] {
+	case "y":
+		return "FREQ=YEARLY", true
+	case "d":
+		if parts[1] == "1" {
+			return "FREQ=DAILY", true
+		}
+		return "FREQ=DAILY;INTERVAL=" + parts[1], true
+	case "w":
+		var days []string
+		for _, s := range strings.Split(parts[1], ",") {
+			n, _ := strconv.Atoi(s)
+			days = append(days, weekdays[n-1])
+		}
+		return "FREQ=WEEKLY;BYDAY=" + strings.Join(days, ","), true
+	case "m":
+		rule := "FREQ=MONTHLY;BYMONTHDAY=" + parts[1]
+		if len(parts) == 3 {
+			rule += ";BYMONTH=" + parts[2]
+		}
+		return rule, true
+	}
+	return "", false
+}
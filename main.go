@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,15 +10,28 @@ import (
 	"syscall"
 
 	"github.com/deeramster/go_final_project/auth"
+	"github.com/deeramster/go_final_project/autobackup"
 	"github.com/deeramster/go_final_project/config"
 	"github.com/deeramster/go_final_project/db"
 	"github.com/deeramster/go_final_project/handlers"
+	"github.com/deeramster/go_final_project/taskdb"
 )
 
 func main() {
 	// Load environment variables
 	config.LoadConfig()
 
+	// Run a maintenance command instead of the server if one is given
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			if !errors.Is(err, errUsage) {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Get the configured port from the config
 	port := config.AppConfig.Port
 
@@ -27,6 +41,10 @@ func main() {
 	// Ensure the database is closed when the application exits
 	defer db.CloseDB()
 
+	// Take scheduled backups; stopped before the database is closed
+	autobackup.Start()
+	defer autobackup.Stop()
+
 	// Static files (serves files from the "web" directory)
 	http.Handle("/", http.FileServer(http.Dir("./web")))
 
@@ -36,6 +54,39 @@ func main() {
 	http.HandleFunc("/api/tasks", auth.Middleware(handlers.HandleTasks))
 	http.HandleFunc("/api/task/done", auth.Middleware(handlers.HandleTaskDone))
 	http.HandleFunc("/api/nextdate", handlers.HandleNextDate)
+	http.HandleFunc("/api/task/attachments", auth.Middleware(handlers.HandleTaskAttachments))
+	http.HandleFunc("/api/attachment", auth.Middleware(handlers.HandleAttachment))
+	http.HandleFunc("/api/dependencies", auth.Middleware(handlers.HandleDependencies))
+	http.HandleFunc("/api/subtasks", auth.Middleware(handlers.HandleSubtasks))
+	http.HandleFunc("/api/subtasks/toggle", auth.Middleware(handlers.HandleSubtaskToggle))
+	http.HandleFunc("/api/subtasks/reorder", auth.Middleware(handlers.HandleSubtaskReorder))
+	http.HandleFunc("/api/tags", auth.Middleware(handlers.HandleTags))
+	http.HandleFunc("/api/tags/rename", auth.Middleware(handlers.HandleTagRename))
+	http.HandleFunc("/api/tags/merge", auth.Middleware(handlers.HandleTagMerge))
+	http.HandleFunc("/api/projects", auth.Middleware(handlers.HandleProjects))
+	http.HandleFunc("/api/projects/move", auth.Middleware(handlers.HandleProjectMove))
+	http.HandleFunc("/api/export", auth.Middleware(handlers.HandleExport))
+	http.HandleFunc("/api/import", auth.Middleware(handlers.HandleImport))
+	http.HandleFunc("/api/export.csv", auth.Middleware(handlers.HandleExportCSV))
+	http.HandleFunc("/api/import/csv", auth.Middleware(handlers.HandleImportCSV))
+	http.HandleFunc("/api/export.txt", auth.Middleware(handlers.HandleExportTodoTxt))
+	http.HandleFunc("/api/import/todotxt", auth.Middleware(handlers.HandleImportTodoTxt))
+	http.HandleFunc("/api/import/ics", auth.Middleware(handlers.HandleImportICS))
+	http.HandleFunc("/api/import/google", auth.Middleware(handlers.HandleImportGoogleTasks))
+	http.HandleFunc("/api/import/todoist", auth.Middleware(handlers.HandleImportTodoist))
+	http.HandleFunc("/api/agenda.md", auth.Middleware(handlers.HandleAgenda))
+	http.HandleFunc("/api/feeds", auth.Middleware(handlers.HandleFeeds))
+
+	// Machine feeds also accept their own token in the URL
+	http.HandleFunc("/api/calendar.ics", auth.FeedMiddleware(taskdb.FeedCalendar, handlers.HandleCalendar))
+	http.HandleFunc("/api/tasks.atom", auth.FeedMiddleware(taskdb.FeedAtom, handlers.HandleAtomFeed))
+	http.HandleFunc("/caldav/", auth.FeedMiddleware(taskdb.FeedCalDAV, handlers.HandleCalDAV))
+	http.Handle("/.well-known/caldav", http.RedirectHandler("/caldav/", http.StatusMovedPermanently))
+
+	// Admin endpoints use a separate token (TODO_ADMIN_TOKEN)
+	http.HandleFunc("/api/admin/backup", auth.AdminMiddleware(handlers.HandleBackup))
+	http.HandleFunc("/api/admin/restore", auth.AdminMiddleware(handlers.HandleRestore))
+	http.HandleFunc("/api/admin/backups", auth.AdminMiddleware(handlers.HandleBackups))
 
 	// Start the server in a separate goroutine
 	go func() {
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// ExportFormat identifies an export document
+const ExportFormat = "go_final_project/tasks"
+
+// ExportVersion is the version of the export document layout
+const ExportVersion = 1
+
+// Export is a portable snapshot of all tasks and their metadata.
+// Ids are only meaningful inside the document: they link tasks to projects
+// and to their blockers, and are reassigned on import.
+type Export struct {
+	Format     string          `json:"format"`
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Projects   []ExportProject `json:"projects"`
+	Tasks      []ExportTask    `json:"tasks"`
+}
+
+// ExportProject is a project in an export document
+type ExportProject struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Color    string `json:"color,omitempty"`
+	Position int    `json:"position"`
+}
+
+// ExportTask is a task in an export document
+type ExportTask struct {
+	ID        string          `json:"id"`
+	Date      string          `json:"date"`
+	Title     string          `json:"title"`
+	Comment   string          `json:"comment,omitempty"`
+	Repeat    string          `json:"repeat,omitempty"`
+	Priority  int             `json:"priority,omitempty"`
+	ProjectID *int64          `json:"project_id,omitempty"`
+	Tags      []string        `json:"tags,omitempty"`
+	Subtasks  []ExportSubtask `json:"subtasks,omitempty"`
+	BlockedBy []string        `json:"blocked_by,omitempty"`
+}
+
+// ExportSubtask is a checklist item in an export document, in display order
+type ExportSubtask struct {
+	Title string `json:"title"`
+	Done  bool   `json:"done,omitempty"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// Project is a named list of tasks
+type Project struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	Position  int    `json:"position"`
+	TaskCount int    `json:"task_count"`
+}
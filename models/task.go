@@ -1,9 +1,56 @@
 package models
 
 type Task struct {
-	ID      string `json:"id"`
-	Date    string `json:"date"`
-	Title   string `json:"title,omitempty" binding:"required"`
-	Comment string `json:"comment"`
-	Repeat  string `json:"repeat"`
+	ID      string   `json:"id"`
+	Date    string   `json:"date"`
+	Title   string   `json:"title,omitempty" binding:"required"`
+	Comment string   `json:"comment"`
+	Repeat  string   `json:"repeat"`
+	Snippet string   `json:"snippet,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// ProjectID is nil for tasks in the inbox; on update nil keeps the project
+	ProjectID *int64 `json:"project_id,omitempty"`
+	// Priority is 1 (most urgent) to 4, nil for none; on update nil keeps it and 0 clears it
+	Priority *int `json:"priority,omitempty"`
+	// Progress counts checklist items; nil when the task has none
+	Progress *Progress `json:"progress,omitempty"`
+	// Subtasks is the checklist, filled only for a single task
+	Subtasks []Subtask `json:"subtasks,omitempty"`
+	// BlockedBy lists tasks that must be done first, Blocks the tasks waiting for this one
+	BlockedBy []string `json:"blocked_by,omitempty"`
+	Blocks    []string `json:"blocks,omitempty"`
+	// Version grows on every change of the task; it is also sent as the ETag
+	Version int64 `json:"version,string,omitempty"`
+}
+
+// Subtask is a checklist item of a task
+type Subtask struct {
+	ID       int64  `json:"id"`
+	TaskID   string `json:"task_id"`
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Position int    `json:"position"`
+}
+
+// Progress is the number of done checklist items out of the total
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Tag is a task label with the number of tasks carrying it
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Attachment is a file attached to a task
+type Attachment struct {
+	ID          int64  `json:"id"`
+	TaskID      string `json:"task_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	CreatedAt   string `json:"created_at"`
+	StoredName  string `json:"-"`
 }
@@ -0,0 +1,339 @@
+package searchlang
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fields understood in "field:value" terms
+const (
+	FieldText     = "" // bare word or phrase, matched against title and comment
+	FieldTitle    = "title"
+	FieldComment  = "comment"
+	FieldRepeat   = "repeat"
+	FieldTag      = "tag"
+	FieldPriority = "priority"
+	FieldBefore   = "before"
+	FieldAfter    = "after"
+	FieldDate     = "date"
+)
+
+var fields = map[string]bool{
+	FieldTitle:    true,
+	FieldComment:  true,
+	FieldRepeat:   true,
+	FieldTag:      true,
+	FieldPriority: true,
+	FieldBefore:   true,
+	FieldAfter:    true,
+	FieldDate:     true,
+}
+
+// dateLayouts are the accepted formats of before:, after: and date: values
+var dateLayouts = []string{"02.01.2006", "20060102"}
+
+// Node is an element of the query AST
+type Node interface {
+	node()
+}
+
+// And matches when all of its nodes match
+type And struct {
+	Nodes []Node
+}
+
+// Or matches when any of its nodes matches
+type Or struct {
+	Nodes []Node
+}
+
+// Not matches when its node doesn't
+type Not struct {
+	Node Node
+}
+
+// Term is a single condition. Dates in Value are normalized to YYYYMMDD.
+type Term struct {
+	Field  string
+	Value  string
+	Phrase bool // the value was quoted
+}
+
+func (And) node()  {}
+func (Or) node()   {}
+func (Not) node()  {}
+func (Term) node() {}
+
+// SyntaxError describes a malformed query
+type SyntaxError struct {
+	Pos int // byte offset in the query
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("позиция %d: %s", e.Pos+1, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField
+	tokMinus
+	tokOr
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind  tokenKind
+	pos   int
+	text  string // word or phrase text, field name for tokField
+	value Term   // field value for tokField
+}
+
+// Parse parses a search query:
+//
+//	отчёт "годовой план"    both words (implicit AND), quoted phrase
+//	title:отчёт comment:…   match a single column
+//	repeat:w repeat:"d 7"   rule type or exact rule
+//	tag:работа              tagged with
+//	priority:1              priority level, 0 for none
+//	before:01.12.2026       date before, after: or date: on a day
+//	-отпуск                 exclude
+//	отчёт OR план           either; parentheses group
+func Parse(query string) (Node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &SyntaxError{tok.pos, "неожиданная закрывающая скобка"}
+	}
+	return node, nil
+}
+
+// PlainWords returns the words of a query made only of bare words,
+// which is searched as free text rather than compiled condition by condition
+func PlainWords(node Node) ([]string, bool) {
+	switch n := node.(type) {
+	case Term:
+		if n.Field != FieldText || n.Phrase {
+			return nil, false
+		}
+		return []string{n.Value}, true
+	case And:
+		var words []string
+		for _, child := range n.Nodes {
+			childWords, ok := PlainWords(child)
+			if !ok {
+				return nil, false
+			}
+			words = append(words, childWords...)
+		}
+		return words, true
+	default:
+		return nil, false
+	}
+}
+
+func lex(query string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, pos: i})
+			i++
+		case c == '"':
+			text, next, err := lexPhrase(query, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokPhrase, pos: i, text: text})
+			i = next
+		case c == '-' && i+1 < len(query) && !isSpace(query[i+1]):
+			tokens = append(tokens, token{kind: tokMinus, pos: i})
+			i++
+		default:
+			start := i
+			for i < len(query) && !isSpace(query[i]) && !strings.ContainsRune(`()"`, rune(query[i])) {
+				i++
+			}
+			word := query[start:i]
+			if word == "OR" {
+				tokens = append(tokens, token{kind: tokOr, pos: start})
+				continue
+			}
+
+			name, value, isField := strings.Cut(word, ":")
+			if !isField || !fields[strings.ToLower(name)] {
+				// Not a known field, e.g. "16:00" in a title
+				tokens = append(tokens, token{kind: tokWord, pos: start, text: word})
+				continue
+			}
+
+			term := Term{Field: strings.ToLower(name), Value: value}
+			if value == "" && i < len(query) && query[i] == '"' {
+				text, next, err := lexPhrase(query, i)
+				if err != nil {
+					return nil, err
+				}
+				term.Value, term.Phrase = text, true
+				i = next
+			}
+			if err := normalizeTerm(&term, start); err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokField, pos: start, text: term.Field, value: term})
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(query)}), nil
+}
+
+// lexPhrase reads a quoted phrase starting at query[start] == '"'
+func lexPhrase(query string, start int) (string, int, error) {
+	end := strings.IndexByte(query[start+1:], '"')
+	if end < 0 {
+		return "", 0, &SyntaxError{start, "не закрыта кавычка"}
+	}
+	text := query[start+1 : start+1+end]
+	if strings.TrimSpace(text) == "" {
+		return "", 0, &SyntaxError{start, "пустая фраза в кавычках"}
+	}
+	return text, start + end + 2, nil
+}
+
+// normalizeTerm checks the value of a field term and converts dates to YYYYMMDD
+func normalizeTerm(term *Term, pos int) error {
+	if strings.TrimSpace(term.Value) == "" {
+		return &SyntaxError{pos, fmt.Sprintf("не указано значение для %s:", term.Field)}
+	}
+
+	switch term.Field {
+	case FieldBefore, FieldAfter, FieldDate:
+		for _, layout := range dateLayouts {
+			if date, err := time.Parse(layout, term.Value); err == nil {
+				term.Value = date.Format("20060102")
+				return nil
+			}
+		}
+		return &SyntaxError{pos, fmt.Sprintf("неверная дата для %s:, ожидается ДД.ММ.ГГГГ", term.Field)}
+	case FieldPriority:
+		if len(term.Value) != 1 || term.Value < "0" || term.Value > "4" {
+			return &SyntaxError{pos, "неверный приоритет, ожидается число от 0 до 4"}
+		}
+	}
+	return nil
+}
+
+// isSpace works on bytes, so only ASCII whitespace counts:
+// continuation bytes of UTF-8 letters must not split words
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{first}
+	for p.peek().kind == tokOr {
+		p.next()
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return first, nil
+	}
+	return Or{Nodes: nodes}, nil
+}
+
+// parseAnd parses one or more unary terms joined implicitly by AND
+func (p *parser) parseAnd() (Node, error) {
+	var nodes []Node
+	for {
+		switch p.peek().kind {
+		case tokEOF, tokOr, tokRParen:
+			if len(nodes) == 0 {
+				return nil, &SyntaxError{p.peek().pos, "ожидается условие поиска"}
+			}
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return And{Nodes: nodes}, nil
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+// parseUnary parses: "-" unary | "(" or ")" | term
+func (p *parser) parseUnary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokMinus:
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Node: node}, nil
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, &SyntaxError{tok.pos, "не закрыта скобка"}
+		}
+		p.next()
+		return node, nil
+	case tokWord:
+		return Term{Field: FieldText, Value: tok.text}, nil
+	case tokPhrase:
+		return Term{Field: FieldText, Value: tok.text, Phrase: true}, nil
+	case tokField:
+		return tok.value, nil
+	default:
+		return nil, &SyntaxError{tok.pos, "ожидается условие поиска"}
+	}
+}
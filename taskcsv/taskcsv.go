@@ -0,0 +1,290 @@
+// Package taskcsv converts tasks to and from CSV spreadsheets.
+package taskcsv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/deeramster/go_final_project/models"
+)
+
+// Task fields a CSV column can hold
+const (
+	FieldTitle    = "title"
+	FieldDate     = "date"
+	FieldComment  = "comment"
+	FieldRepeat   = "repeat"
+	FieldPriority = "priority"
+	FieldProject  = "project"
+	FieldTags     = "tags"
+)
+
+// Fields lists the task fields in the column order of exported files
+var Fields = []string{FieldTitle, FieldDate, FieldComment, FieldRepeat, FieldPriority, FieldProject, FieldTags}
+
+// DateFormats maps the date format names accepted from users to time layouts
+var DateFormats = map[string]string{
+	"YYYYMMDD":   dateutil.DateLayout,
+	"YYYY-MM-DD": "2006-01-02",
+	"YYYY/MM/DD": "2006/01/02",
+	"DD.MM.YYYY": "02.01.2006",
+	"DD/MM/YYYY": "02/01/2006",
+	"MM/DD/YYYY": "01/02/2006",
+}
+
+// DefaultDateFormat is used for export when no format is given
+const DefaultDateFormat = "YYYY-MM-DD"
+
+// autoDateFormats are tried in order when no format is given on import;
+// slashed day-month dates are ambiguous and need an explicit format
+var autoDateFormats = []string{"YYYY-MM-DD", "DD.MM.YYYY", "YYYYMMDD", "YYYY/MM/DD"}
+
+// bom is the UTF-8 byte order mark Excel needs to recognise UTF-8 files
+var bom = []byte("\xef\xbb\xbf")
+
+// formulaPrefixes start cells that spreadsheets evaluate as formulas
+const formulaPrefixes = "=+-@\t\r"
+
+// ErrNoTitleColumn is returned when the header has no column mapped to the title
+var ErrNoTitleColumn = errors.New("no title column in the header")
+
+// Options control the CSV dialect and how columns map to task fields
+type Options struct {
+	// Comma is the field delimiter, ',' when zero
+	Comma rune
+	// DateFormat is a key of DateFormats; empty means the default for export
+	// and trying the unambiguous formats on import
+	DateFormat string
+	// Columns maps task fields to header names; unmapped fields use their own name
+	Columns map[string]string
+}
+
+func (o Options) comma() rune {
+	if o.Comma == 0 {
+		return ','
+	}
+	return o.Comma
+}
+
+// RowError describes a row that could not be converted to a task
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// Write writes the tasks of doc as CSV with a header row, prefixed with a BOM for Excel
+func Write(w io.Writer, doc models.Export, opts Options) error {
+	format := opts.DateFormat
+	if format == "" {
+		format = DefaultDateFormat
+	}
+	layout, ok := DateFormats[format]
+	if !ok {
+		return fmt.Errorf("unknown date format %q", format)
+	}
+
+	projects := make(map[int64]string)
+	for _, p := range doc.Projects {
+		projects[p.ID] = p.Name
+	}
+
+	if _, err := w.Write(bom); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.comma()
+
+	header := make([]string, len(Fields))
+	for i, field := range Fields {
+		header[i] = columnName(opts, field)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, t := range doc.Tasks {
+		date := t.Date
+		if d, err := time.Parse(dateutil.DateLayout, t.Date); err == nil {
+			date = d.Format(layout)
+		}
+		var priority, project string
+		if t.Priority != 0 {
+			priority = strconv.Itoa(t.Priority)
+		}
+		if t.ProjectID != nil {
+			project = projects[*t.ProjectID]
+		}
+		record := []string{escapeCell(t.Title), date, escapeCell(t.Comment), t.Repeat, priority,
+			escapeCell(project), escapeCell(strings.Join(t.Tags, ", "))}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Read converts CSV rows to an export document ready for import.
+// Rows whose date or priority can't be read are reported and left out; rows
+// is the line number of each returned task. Empty rows are ignored.
+func Read(r io.Reader, opts Options) (doc models.Export, rows []int, rowErrors []RowError, err error) {
+	doc = models.Export{Format: models.ExportFormat, Version: models.ExportVersion, Projects: []models.ExportProject{}, Tasks: []models.ExportTask{}}
+
+	var layouts []string
+	if opts.DateFormat != "" {
+		layout, ok := DateFormats[opts.DateFormat]
+		if !ok {
+			return doc, nil, nil, fmt.Errorf("unknown date format %q", opts.DateFormat)
+		}
+		layouts = []string{layout}
+	} else {
+		for _, format := range autoDateFormats {
+			layouts = append(layouts, DateFormats[format])
+		}
+	}
+
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(bom)); bytes.Equal(head, bom) {
+		br.Discard(len(bom))
+	}
+	cr := csv.NewReader(br)
+	cr.Comma = opts.comma()
+	cr.FieldsPerRecord = -1
+	// Trimming would also eat empty fields between whitespace delimiters
+	cr.TrimLeadingSpace = !unicode.IsSpace(cr.Comma)
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return doc, nil, nil, ErrNoTitleColumn
+	}
+	if err != nil {
+		return doc, nil, nil, err
+	}
+
+	// columns maps each task field to its index in a record
+	columns := make(map[string]int)
+	for _, field := range Fields {
+		name := columnName(opts, field)
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				columns[field] = i
+				break
+			}
+		}
+	}
+	if _, ok := columns[FieldTitle]; !ok {
+		return doc, nil, nil, ErrNoTitleColumn
+	}
+
+	projects := make(map[string]int64)
+	today := time.Now().Format(dateutil.DateLayout)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return doc, nil, nil, err
+		}
+		row, _ := cr.FieldPos(0)
+
+		value := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(unescapeCell(strings.TrimSpace(record[i])))
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		t := models.ExportTask{
+			ID:      strconv.Itoa(row),
+			Title:   value(FieldTitle),
+			Comment: value(FieldComment),
+			Repeat:  value(FieldRepeat),
+			Date:    today,
+		}
+		if s := value(FieldDate); s != "" {
+			date, err := parseDate(s, layouts)
+			if err != nil {
+				rowErrors = append(rowErrors, RowError{Row: row, Message: err.Error()})
+				continue
+			}
+			t.Date = date
+		}
+		if s := value(FieldPriority); s != "" {
+			priority, err := strconv.Atoi(s)
+			if err != nil {
+				rowErrors = append(rowErrors, RowError{Row: row, Message: fmt.Sprintf("invalid priority %q", s)})
+				continue
+			}
+			t.Priority = priority
+		}
+		if name := value(FieldProject); name != "" {
+			id, ok := projects[name]
+			if !ok {
+				id = int64(len(projects) + 1)
+				projects[name] = id
+				doc.Projects = append(doc.Projects, models.ExportProject{ID: id, Name: name})
+			}
+			t.ProjectID = &id
+		}
+		for _, tag := range strings.Split(value(FieldTags), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				t.Tags = append(t.Tags, tag)
+			}
+		}
+
+		doc.Tasks = append(doc.Tasks, t)
+		rows = append(rows, row)
+	}
+	return doc, rows, rowErrors, nil
+}
+
+// columnName returns the header name of a task field
+func columnName(opts Options, field string) string {
+	if name := opts.Columns[field]; name != "" {
+		return name
+	}
+	return field
+}
+
+// escapeCell quotes text a spreadsheet would run as a formula with a leading
+// apostrophe, which Excel and LibreOffice hide and keep the cell as text.
+// Text already starting with an apostrophe gets one more, so that Read can
+// strip exactly one.
+func escapeCell(s string) string {
+	if s != "" && (strings.ContainsRune(formulaPrefixes, rune(s[0])) || s[0] == '\'') {
+		return "'" + s
+	}
+	return s
+}
+
+// unescapeCell strips the apostrophe escapeCell adds; apostrophes before
+// ordinary text are kept, as files from other programs don't escape them
+func unescapeCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && (strings.ContainsRune(formulaPrefixes, rune(s[1])) || s[1] == '\'') {
+		return s[1:]
+	}
+	return s
+}
+
+// parseDate reads a date in the first matching layout and returns it as YYYYMMDD
+func parseDate(s string, layouts []string) (string, error) {
+	for _, layout := range layouts {
+		if d, err := time.Parse(layout, s); err == nil {
+			return d.Format(dateutil.DateLayout), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", s)
+}
@@ -0,0 +1,130 @@
+package taskdb
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/filestore"
+	"github.com/deeramster/go_final_project/models"
+)
+
+// ErrAttachmentNotFound is returned for an attachment id that doesn't exist
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+const attachmentColumns = "id, task_id, name, content_type, size, created_at, stored_name"
+
+func scanAttachment(row rowScanner) (models.Attachment, error) {
+	var a models.Attachment
+	err := row.Scan(&a.ID, &a.TaskID, &a.Name, &a.ContentType, &a.Size, &a.CreatedAt, &a.StoredName)
+	return a, err
+}
+
+// AddAttachment records metadata of a file already saved with filestore.Save
+func AddAttachment(a models.Attachment) (int64, error) {
+	taskID, err := strconv.ParseInt(a.TaskID, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO attachments (task_id, name, content_type, size, stored_name) VALUES (?, ?, ?, ?, ?)"
+	result, err := tx.Exec(query, taskID, a.Name, a.ContentType, a.Size, a.StoredName)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := bumpTask(tx, taskID); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// AttachmentCountsFrom counts the attachments of each task in another scheduler
+// database, such as one being merged in, keyed by task id as ExportFrom writes it.
+// Exports carry no files, so these attachments are left behind.
+func AttachmentCountsFrom(conn *sql.DB) (map[string]int, error) {
+	rows, err := conn.Query("SELECT task_id, count(*) FROM attachments GROUP BY task_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var taskID string
+		var n int
+		if err := rows.Scan(&taskID, &n); err != nil {
+			return nil, err
+		}
+		counts[taskID] = n
+	}
+	return counts, rows.Err()
+}
+
+// GetAttachments returns the attachments of a task, oldest first
+func GetAttachments(taskID int) ([]models.Attachment, error) {
+	rows, err := db.GetDB().Query("SELECT "+attachmentColumns+" FROM attachments WHERE task_id = ? ORDER BY id", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// GetAttachmentByID retrieves attachment metadata by its ID
+func GetAttachmentByID(attachmentID int64) (models.Attachment, error) {
+	a, err := scanAttachment(db.GetDB().QueryRow("SELECT "+attachmentColumns+" FROM attachments WHERE id = ?", attachmentID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Attachment{}, ErrAttachmentNotFound
+	}
+	return a, err
+}
+
+// DeleteAttachment deletes an attachment and its file
+func DeleteAttachment(attachmentID int64) error {
+	a, err := GetAttachmentByID(attachmentID)
+	if err != nil {
+		return err
+	}
+	taskID, err := strconv.ParseInt(a.TaskID, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM attachments WHERE id = ?", attachmentID); err != nil {
+		return err
+	}
+	if err := bumpTask(tx, taskID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	filestore.Remove(a.StoredName)
+	return nil
+}
@@ -0,0 +1,182 @@
+package taskdb
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/models"
+)
+
+// ErrResourceExists is returned when a CalDAV client creates a resource under a taken name
+var ErrResourceExists = errors.New("resource already exists")
+
+// CalDAVTask is a task with the resource name it has in the CalDAV collection.
+// UID is the UID the client created it with, empty for tasks created otherwise.
+type CalDAVTask struct {
+	models.Task
+	Name string
+	UID  string
+}
+
+// SyncToken returns the number of the latest task change; it only grows
+func SyncToken() (int64, error) {
+	var seq int64
+	err := db.GetDB().QueryRow("SELECT IFNULL(max(seq), 0) FROM task_changes").Scan(&seq)
+	return seq, err
+}
+
+// CalDAVTasks returns all tasks with their resource names
+func CalDAVTasks() ([]CalDAVTask, error) {
+	tasks, err := NewTaskQuery().All()
+	if err != nil {
+		return nil, err
+	}
+	return withResources(tasks)
+}
+
+// FindCalDAVTask returns the task behind a resource name, or ErrNotFound.
+// Tasks created by clients are found by the name they chose, the others by id.
+func FindCalDAVTask(name string) (CalDAVTask, error) {
+	var taskID int64
+	err := db.GetDB().QueryRow("SELECT task_id FROM caldav_resources WHERE name = ?", name).Scan(&taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		taskID, err = strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			return CalDAVTask{}, ErrNotFound
+		}
+	} else if err != nil {
+		return CalDAVTask{}, err
+	}
+
+	task, err := GetTaskByID(int(taskID))
+	if err != nil {
+		return CalDAVTask{}, err
+	}
+	tasks, err := withResources([]models.Task{task})
+	if err != nil {
+		return CalDAVTask{}, err
+	}
+	if tasks[0].Name != name {
+		// The id of a task created by a client isn't its resource name
+		return CalDAVTask{}, ErrNotFound
+	}
+	return tasks[0], nil
+}
+
+// AddCalDAVTask adds a task created by a CalDAV client under a resource name
+// and returns its id. A name left over from a deleted task is reused.
+func AddCalDAVTask(task models.Task, name, uid string) (int64, error) {
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := "DELETE FROM caldav_resources WHERE name = ? AND task_id NOT IN (SELECT id FROM scheduler)"
+	if _, err := tx.Exec(query, name); err != nil {
+		return 0, err
+	}
+	var taken bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM caldav_resources WHERE name = ?)", name).Scan(&taken); err != nil {
+		return 0, err
+	}
+	if _, err := strconv.ParseInt(name, 10, 64); taken || err == nil {
+		// Numeric names are the ids of other tasks
+		return 0, ErrResourceExists
+	}
+
+	id, err := insertTask(tx, task)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("INSERT INTO caldav_resources (task_id, name, uid) VALUES (?, ?, ?)", id, name, uid); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// TaskChanges returns the tasks changed after the sync token since and the
+// resource names of the tasks deleted since then, up to the token latest
+func TaskChanges(since, latest int64) (changed []CalDAVTask, deleted []string, err error) {
+	query := `
+  SELECT c.task_id, c.deleted, r.name
+  FROM task_changes c
+  LEFT JOIN caldav_resources r ON r.task_id = c.task_id
+  WHERE c.seq > ? AND c.seq <= ?
+  ORDER BY c.seq
+ `
+	rows, err := db.GetDB().Query(query, since, latest)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var taskID int
+		var isDeleted bool
+		var name sql.NullString
+		if err := rows.Scan(&taskID, &isDeleted, &name); err != nil {
+			return nil, nil, err
+		}
+		if !isDeleted {
+			ids = append(ids, taskID)
+		} else if name.Valid {
+			deleted = append(deleted, name.String)
+		} else {
+			deleted = append(deleted, strconv.Itoa(taskID))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
+	tasks := make([]models.Task, 0, len(ids))
+	for _, id := range ids {
+		task, err := GetTaskByID(id)
+		if errors.Is(err, ErrNotFound) {
+			// Deleted after latest; the next sync reports it
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	changed, err = withResources(tasks)
+	return changed, deleted, err
+}
+
+// withResources adds resource names and UIDs to tasks
+func withResources(tasks []models.Task) ([]CalDAVTask, error) {
+	result := make([]CalDAVTask, len(tasks))
+	for i, task := range tasks {
+		result[i] = CalDAVTask{Task: task, Name: task.ID}
+	}
+	if len(tasks) == 0 {
+		return result, nil
+	}
+
+	index, args := taskIndex(tasks)
+	query := "SELECT task_id, name, uid FROM caldav_resources WHERE task_id IN (" + placeholders(len(args)) + ")"
+	rows, err := db.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, name, uid string
+		if err := rows.Scan(&taskID, &name, &uid); err != nil {
+			return nil, err
+		}
+		if i, ok := index[taskID]; ok {
+			result[i].Name = name
+			result[i].UID = uid
+		}
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,151 @@
+package taskdb
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/models"
+)
+
+var (
+	// ErrDependencyCycle is returned when a dependency would make tasks wait for each other
+	ErrDependencyCycle = errors.New("dependency would create a cycle")
+	// ErrDependencyNotFound is returned when removing a dependency that doesn't exist
+	ErrDependencyNotFound = errors.New("dependency not found")
+)
+
+// AddDependency records that taskID can't be done until blockerID is.
+// Dependencies go away when the blocker is deleted or, for a recurring
+// blocker, completed once, so every stored blocker is open.
+func AddDependency(taskID, blockerID int64) error {
+	if taskID == blockerID {
+		return ErrDependencyCycle
+	}
+
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var found int
+	if err := tx.QueryRow("SELECT count(*) FROM scheduler WHERE id IN (?, ?)", taskID, blockerID).Scan(&found); err != nil {
+		return err
+	}
+	if found != 2 {
+		return ErrNotFound
+	}
+
+	// Walk everything the blocker waits for; meeting taskID there closes a cycle
+	query := `
+  WITH RECURSIVE upstream(id) AS (
+   SELECT ?
+   UNION
+   SELECT d.blocker_id FROM task_dependencies d JOIN upstream u ON d.task_id = u.id
+  )
+  SELECT EXISTS (SELECT 1 FROM upstream WHERE id = ?)
+ `
+	var cycle bool
+	if err := tx.QueryRow(query, blockerID, taskID).Scan(&cycle); err != nil {
+		return err
+	}
+	if cycle {
+		return ErrDependencyCycle
+	}
+
+	result, err := tx.Exec("INSERT OR IGNORE INTO task_dependencies (task_id, blocker_id) VALUES (?, ?)", taskID, blockerID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		if err := bumpPair(tx, taskID, blockerID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveDependency deletes a blocked-by relationship
+func RemoveDependency(taskID, blockerID int64) error {
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM task_dependencies WHERE task_id = ? AND blocker_id = ?", taskID, blockerID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrDependencyNotFound
+	}
+	if err := bumpPair(tx, taskID, blockerID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// bumpPair bumps both ends of a dependency: the task shows it in blocked_by,
+// the blocker in blocks
+func bumpPair(tx *sql.Tx, taskID, blockerID int64) error {
+	if err := bumpTask(tx, taskID); err != nil {
+		return err
+	}
+	return bumpTask(tx, blockerID)
+}
+
+// bumpLinked bumps the tasks that depend on or block the tasks matching where,
+// before those tasks are deleted and drop out of their blocked_by and blocks
+func bumpLinked(tx *sql.Tx, where string, args ...any) error {
+	query := `
+  UPDATE scheduler SET version = version + 1
+  WHERE id IN (
+   SELECT task_id FROM task_dependencies WHERE blocker_id IN (SELECT id FROM scheduler WHERE ` + where + `)
+   UNION
+   SELECT blocker_id FROM task_dependencies WHERE task_id IN (SELECT id FROM scheduler WHERE ` + where + `)
+  )
+ `
+	_, err := tx.Exec(query, append(append([]any{}, args...), args...)...)
+	return err
+}
+
+// attachDependencies loads blocked_by and blocks of the given tasks in one query
+func attachDependencies(tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index, args := taskIndex(tasks)
+	in := placeholders(len(args))
+	query := `
+  SELECT task_id, blocker_id
+  FROM task_dependencies
+  WHERE task_id IN (` + in + `) OR blocker_id IN (` + in + `)
+  ORDER BY task_id, blocker_id
+ `
+	rows, err := db.GetDB().Query(query, append(args, args...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, blockerID string
+		if err := rows.Scan(&taskID, &blockerID); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			tasks[i].BlockedBy = append(tasks[i].BlockedBy, blockerID)
+		}
+		if i, ok := index[blockerID]; ok {
+			tasks[i].Blocks = append(tasks[i].Blocks, taskID)
+		}
+	}
+	return rows.Err()
+}
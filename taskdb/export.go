@@ -0,0 +1,509 @@
+package taskdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/filestore"
+	"github.com/deeramster/go_final_project/models"
+)
+
+// ImportMode says what happens to existing tasks on import
+type ImportMode string
+
+const (
+	// ImportMerge keeps existing tasks and skips imported duplicates of them
+	ImportMerge ImportMode = "merge"
+	// ImportReplace deletes all tasks and projects before importing
+	ImportReplace ImportMode = "replace"
+)
+
+var (
+	// ErrUnsupportedExport is returned for a document of another format or a newer version
+	ErrUnsupportedExport = errors.New("unsupported export document")
+	// ErrInvalidImport is returned when some tasks of a document fail validation;
+	// the result lists them and nothing is written
+	ErrInvalidImport = errors.New("import document has invalid tasks")
+	// ErrInvalidMatch is returned for an unknown or empty list of match fields
+	ErrInvalidMatch = errors.New("match fields must be some of title, date and repeat")
+)
+
+// Fields that can make an imported task a duplicate of a stored one
+const (
+	MatchTitle  = "title"
+	MatchDate   = "date"
+	MatchRepeat = "repeat"
+)
+
+// DefaultMatch is used when ImportOptions.Match is empty
+var DefaultMatch = []string{MatchTitle, MatchDate, MatchRepeat}
+
+// ImportOptions control how ImportTasks treats existing and invalid tasks
+type ImportOptions struct {
+	Mode   ImportMode
+	DryRun bool
+	// SkipInvalid imports the valid tasks of a document that has invalid ones
+	SkipInvalid bool
+	// Match lists the fields a task must share with a stored one to be skipped as its duplicate
+	Match []string
+}
+
+// ImportIssue describes a problem with the task at Index in the imported document;
+// Index is -1 for problems outside the task list
+type ImportIssue struct {
+	Index int `json:"index"`
+	// Row is the line of the task in the source file, for formats that have lines
+	Row int `json:"row,omitempty"`
+	// Project is set instead of Index for a problem with a project
+	Project *int64 `json:"project,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult reports what an import did, or would do on a dry run
+type ImportResult struct {
+	Mode            ImportMode    `json:"mode"`
+	DryRun          bool          `json:"dry_run"`
+	Created         int           `json:"created"`
+	Skipped         int           `json:"skipped"`
+	Deleted         int64         `json:"deleted"`
+	Invalid         int           `json:"invalid"`
+	ProjectsCreated int           `json:"projects_created"`
+	Duplicates      []ImportIssue `json:"duplicates,omitempty"`
+	// Conflicts are skipped tasks that match a stored one but differ from it in other fields
+	Conflicts []ImportIssue `json:"conflicts,omitempty"`
+	Errors    []ImportIssue `json:"errors,omitempty"`
+	// Warnings list details lost converting from a foreign format; they don't stop the import
+	Warnings []ImportIssue `json:"warnings,omitempty"`
+}
+
+// storedTask is what an imported task is compared with to find duplicates and conflicts
+type storedTask struct {
+	id                           int64
+	title, date, repeat, comment string
+	priority                     int
+}
+
+// taskKey identifies tasks that count as duplicates of each other by the match fields
+func taskKey(match []string, t storedTask) string {
+	var key string
+	for _, field := range match {
+		switch field {
+		case MatchTitle:
+			key += t.title
+		case MatchDate:
+			key += t.date
+		case MatchRepeat:
+			key += t.repeat
+		}
+		key += "\x00"
+	}
+	return key
+}
+
+// differences lists the fields in which a duplicate differs from the stored task
+func differences(stored, imported storedTask) []string {
+	var fields []string
+	for _, f := range []struct {
+		name  string
+		equal bool
+	}{
+		{MatchTitle, stored.title == imported.title},
+		{MatchDate, stored.date == imported.date},
+		{MatchRepeat, stored.repeat == imported.repeat},
+		{"comment", stored.comment == imported.comment},
+		{"priority", stored.priority == imported.priority},
+	} {
+		if !f.equal {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// ExportTasks returns all tasks with their projects, tags, checklists and dependencies
+func ExportTasks() (models.Export, error) {
+	return ExportFrom(db.GetDB())
+}
+
+// ExportFrom is ExportTasks for another scheduler database, such as one being merged in
+func ExportFrom(conn *sql.DB) (models.Export, error) {
+	doc := models.Export{
+		Format:     models.ExportFormat,
+		Version:    models.ExportVersion,
+		ExportedAt: time.Now().UTC().Truncate(time.Second),
+		Projects:   []models.ExportProject{},
+		Tasks:      []models.ExportTask{},
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return doc, err
+	}
+	// Reading in one transaction keeps the document consistent
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, name, color, position FROM projects ORDER BY position, id")
+	if err != nil {
+		return doc, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.ExportProject
+		if err := rows.Scan(&p.ID, &p.Name, &p.Color, &p.Position); err != nil {
+			return doc, err
+		}
+		doc.Projects = append(doc.Projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return doc, err
+	}
+
+	rows, err = tx.Query("SELECT id, date, title, comment, repeat, priority, project_id FROM scheduler ORDER BY date, id")
+	if err != nil {
+		return doc, err
+	}
+	defer rows.Close()
+	index := make(map[string]int)
+	for rows.Next() {
+		var t models.ExportTask
+		var projectID sql.NullInt64
+		if err := rows.Scan(&t.ID, &t.Date, &t.Title, &t.Comment, &t.Repeat, &t.Priority, &projectID); err != nil {
+			return doc, err
+		}
+		if projectID.Valid {
+			t.ProjectID = &projectID.Int64
+		}
+		index[t.ID] = len(doc.Tasks)
+		doc.Tasks = append(doc.Tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return doc, err
+	}
+
+	// Details are read for the whole table at once and matched by task id
+	details := []struct {
+		query string
+		add   func(t *models.ExportTask, value string, done bool)
+	}{
+		{
+			"SELECT tt.task_id, t.name, 0 FROM task_tags tt JOIN tags t ON t.id = tt.tag_id ORDER BY t.name",
+			func(t *models.ExportTask, name string, _ bool) { t.Tags = append(t.Tags, name) },
+		},
+		{
+			"SELECT task_id, title, done FROM subtasks ORDER BY task_id, position, id",
+			func(t *models.ExportTask, title string, done bool) {
+				t.Subtasks = append(t.Subtasks, models.ExportSubtask{Title: title, Done: done})
+			},
+		},
+		{
+			"SELECT task_id, blocker_id, 0 FROM task_dependencies ORDER BY task_id, blocker_id",
+			func(t *models.ExportTask, blocker string, _ bool) { t.BlockedBy = append(t.BlockedBy, blocker) },
+		},
+	}
+	for _, d := range details {
+		rows, err := tx.Query(d.query)
+		if err != nil {
+			return doc, err
+		}
+		for rows.Next() {
+			var taskID, value string
+			var done bool
+			if err := rows.Scan(&taskID, &value, &done); err != nil {
+				rows.Close()
+				return doc, err
+			}
+			if i, ok := index[taskID]; ok {
+				d.add(&doc.Tasks[i], value, done)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return doc, err
+		}
+	}
+	return doc, nil
+}
+
+// ImportTasks adds the tasks of an export document in one transaction.
+// In merge mode a task with the same title, date and repeat rule as an
+// existing one (or the same opts.Match fields) is skipped, and projects are
+// matched by name. On a dry run the transaction is rolled back, so the result
+// previews the import.
+func ImportTasks(doc models.Export, opts ImportOptions) (ImportResult, error) {
+	mode := opts.Mode
+	result := ImportResult{Mode: mode, DryRun: opts.DryRun}
+	if doc.Format != models.ExportFormat || doc.Version < 1 || doc.Version > models.ExportVersion {
+		return result, ErrUnsupportedExport
+	}
+	match := opts.Match
+	if len(match) == 0 {
+		match = DefaultMatch
+	}
+	for _, field := range match {
+		if field != MatchTitle && field != MatchDate && field != MatchRepeat {
+			return result, ErrInvalidMatch
+		}
+	}
+
+	// invalid marks the tasks and projects left out with SkipInvalid
+	invalid := make(map[int]bool)
+	invalidProjects := make(map[int64]bool)
+	result.Errors = validateImport(doc)
+	for _, issue := range result.Errors {
+		if issue.Index >= 0 {
+			invalid[issue.Index] = true
+		} else if issue.Project != nil {
+			invalidProjects[*issue.Project] = true
+		}
+	}
+	result.Invalid = len(invalid)
+	if len(result.Errors) > 0 && !opts.SkipInvalid {
+		return result, ErrInvalidImport
+	}
+
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	var files []string
+	if mode == ImportReplace {
+		files, result.Deleted, err = deleteTasks(tx, "1 = 1")
+		if err != nil {
+			return result, err
+		}
+		if _, err := tx.Exec("DELETE FROM projects"); err != nil {
+			return result, err
+		}
+	}
+
+	projects, err := importProjects(tx, doc.Projects, invalidProjects, mode, &result)
+	if err != nil {
+		return result, err
+	}
+
+	existing, err := taskKeys(tx, match)
+	if err != nil {
+		return result, err
+	}
+
+	// ids maps document task ids to database ids, created marks the new tasks
+	ids := make(map[string]int64)
+	created := make(map[string]bool)
+	for i, t := range doc.Tasks {
+		if invalid[i] {
+			continue
+		}
+		imported := storedTask{title: t.Title, date: t.Date, repeat: t.Repeat, comment: t.Comment, priority: t.Priority}
+		key := taskKey(match, imported)
+		if stored, ok := existing[key]; ok {
+			result.Skipped++
+			id := strconv.FormatInt(stored.id, 10)
+			if diff := differences(stored, imported); len(diff) > 0 {
+				message := "matches task " + id + " but differs in " + strings.Join(diff, ", ")
+				result.Conflicts = append(result.Conflicts, ImportIssue{Index: i, Title: t.Title, Message: message})
+			} else {
+				result.Duplicates = append(result.Duplicates, ImportIssue{Index: i, Title: t.Title, Message: "duplicate of task " + id})
+			}
+			if t.ID != "" {
+				ids[t.ID] = stored.id
+			}
+			continue
+		}
+
+		task := models.Task{Title: t.Title, Date: t.Date, Comment: t.Comment, Repeat: t.Repeat, Priority: &t.Priority, Tags: t.Tags}
+		if t.ProjectID != nil {
+			// A project left out as invalid puts the task into the inbox
+			if projectID, ok := projects[*t.ProjectID]; ok {
+				task.ProjectID = &projectID
+			}
+		}
+		id, err := insertTask(tx, task)
+		if err != nil {
+			return result, err
+		}
+		for position, subtask := range t.Subtasks {
+			query := "INSERT INTO subtasks (task_id, title, done, position) VALUES (?, ?, ?, ?)"
+			if _, err := tx.Exec(query, id, subtask.Title, subtask.Done, position+1); err != nil {
+				return result, err
+			}
+		}
+
+		imported.id = id
+		existing[key] = imported
+		if t.ID != "" {
+			ids[t.ID] = id
+			created[t.ID] = true
+		}
+		result.Created++
+	}
+
+	// Dependencies of skipped duplicates stay as they are in the database
+	for _, t := range doc.Tasks {
+		if !created[t.ID] {
+			continue
+		}
+		for _, blocker := range t.BlockedBy {
+			// A blocker left out as invalid has no id
+			blockerID, ok := ids[blocker]
+			if !ok || blockerID == ids[t.ID] {
+				continue
+			}
+			query := "INSERT OR IGNORE INTO task_dependencies (task_id, blocker_id) VALUES (?, ?)"
+			if _, err := tx.Exec(query, ids[t.ID], ids[blocker]); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	filestore.Remove(files...)
+	return result, nil
+}
+
+// importProjects creates the projects of a document and maps their document ids to database ids.
+// A project whose name is already taken is reused; on merge new projects go after the existing ones.
+func importProjects(tx *sql.Tx, projects []models.ExportProject, invalid map[int64]bool, mode ImportMode, result *ImportResult) (map[int64]int64, error) {
+	ids := make(map[int64]int64)
+	for _, p := range projects {
+		if invalid[p.ID] {
+			continue
+		}
+		position := p.Position
+		if mode == ImportMerge {
+			position = 0
+		}
+
+		var id int64
+		err := tx.QueryRow("SELECT id FROM projects WHERE name = ? ORDER BY id LIMIT 1", p.Name).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			query := `
+  INSERT INTO projects (name, color, position)
+  VALUES (?, ?, CASE WHEN ? = 0 THEN (SELECT IFNULL(max(position), 0) + 1 FROM projects) ELSE ? END)
+ `
+			var res sql.Result
+			res, err = tx.Exec(query, p.Name, p.Color, position, position)
+			if err == nil {
+				id, err = res.LastInsertId()
+				result.ProjectsCreated++
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids[p.ID] = id
+	}
+	return ids, nil
+}
+
+// taskKeys maps the duplicate key of every stored task to the task; of
+// several tasks with one key the oldest is kept
+func taskKeys(tx *sql.Tx, match []string) (map[string]storedTask, error) {
+	rows, err := tx.Query("SELECT id, title, date, repeat, comment, priority FROM scheduler ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]storedTask)
+	for rows.Next() {
+		var t storedTask
+		if err := rows.Scan(&t.id, &t.title, &t.date, &t.repeat, &t.comment, &t.priority); err != nil {
+			return nil, err
+		}
+		if _, ok := keys[taskKey(match, t)]; !ok {
+			keys[taskKey(match, t)] = t
+		}
+	}
+	return keys, rows.Err()
+}
+
+// validateImport checks every task of a document the way the task handlers do,
+// plus the references between tasks and projects
+func validateImport(doc models.Export) []ImportIssue {
+	var issues []ImportIssue
+
+	projects := make(map[int64]bool)
+	for _, p := range doc.Projects {
+		if p.Name == "" || projects[p.ID] {
+			id := p.ID
+			issues = append(issues, ImportIssue{Index: -1, Project: &id, Title: p.Name, Message: fmt.Sprintf("project %d has no name or a duplicate id", p.ID)})
+		}
+		projects[p.ID] = true
+	}
+
+	tasks := make(map[string]models.ExportTask)
+	for _, t := range doc.Tasks {
+		if t.ID != "" {
+			tasks[t.ID] = t
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, t := range doc.Tasks {
+		issue := func(format string, args ...any) {
+			issues = append(issues, ImportIssue{Index: i, Title: t.Title, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if t.Title == "" {
+			issue("title is required")
+		}
+		if _, err := time.Parse(dateutil.DateLayout, t.Date); err != nil {
+			issue("invalid date %q, expected YYYYMMDD", t.Date)
+		}
+		if err := dateutil.ValidateRepeat(t.Repeat); err != nil {
+			issue("invalid repeat rule %q: %v", t.Repeat, err)
+		}
+		if t.Priority < 0 || t.Priority > MaxPriority {
+			issue("priority must be between 1 and 4, or 0 for none")
+		}
+		if t.ProjectID != nil && !projects[*t.ProjectID] {
+			issue("unknown project %d", *t.ProjectID)
+		}
+		if t.ID != "" && seen[t.ID] {
+			issue("duplicate task id %q", t.ID)
+		}
+		seen[t.ID] = true
+
+		for _, blocker := range t.BlockedBy {
+			if _, ok := tasks[blocker]; !ok || blocker == t.ID {
+				issue("unknown blocker %q", blocker)
+			}
+		}
+		if t.ID != "" && dependsOn(tasks, t.ID, t.ID, make(map[string]bool)) {
+			issue("dependency cycle")
+		}
+	}
+	return issues
+}
+
+// dependsOn reports whether the task id transitively waits for target
+func dependsOn(tasks map[string]models.ExportTask, id, target string, visited map[string]bool) bool {
+	for _, blocker := range tasks[id].BlockedBy {
+		if blocker == target && blocker != id {
+			return true
+		}
+		if visited[blocker] {
+			continue
+		}
+		visited[blocker] = true
+		if dependsOn(tasks, blocker, target, visited) {
+			return true
+		}
+	}
+	return false
+}
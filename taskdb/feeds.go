@@ -0,0 +1,88 @@
+package taskdb
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"slices"
+
+	"github.com/deeramster/go_final_project/db"
+)
+
+// Machine feeds are read by clients that can't sign in, such as calendar
+// apps, so each one has its own secret token passed in the URL
+const (
+	FeedCalendar = "calendar"
+	// FeedCalDAV is read and written by task apps, which send the token as a Basic auth password
+	FeedCalDAV = "caldav"
+	FeedAtom   = "atom"
+)
+
+// Feeds lists the feeds a token can be issued for
+var Feeds = []string{FeedCalendar, FeedCalDAV, FeedAtom}
+
+var (
+	// ErrUnknownFeed is returned for a feed name not in Feeds
+	ErrUnknownFeed = errors.New("unknown feed")
+	// ErrFeedDisabled is returned when no token has been issued for a feed
+	ErrFeedDisabled = errors.New("feed is disabled")
+)
+
+// FeedToken returns the current token of a feed
+func FeedToken(feed string) (string, error) {
+	var token string
+	err := db.GetDB().QueryRow("SELECT token FROM feed_tokens WHERE feed = ?", feed).Scan(&token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrFeedDisabled
+	}
+	return token, err
+}
+
+// FeedTokens returns the tokens of all enabled feeds by feed name
+func FeedTokens() (map[string]string, error) {
+	rows, err := db.GetDB().Query("SELECT feed, token FROM feed_tokens")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make(map[string]string)
+	for rows.Next() {
+		var feed, token string
+		if err := rows.Scan(&feed, &token); err != nil {
+			return nil, err
+		}
+		tokens[feed] = token
+	}
+	return tokens, rows.Err()
+}
+
+// RotateFeedToken issues a new token for a feed, enabling it and
+// invalidating any token issued before
+func RotateFeedToken(feed string) (string, error) {
+	if !slices.Contains(Feeds, feed) {
+		return "", ErrUnknownFeed
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	query := "INSERT INTO feed_tokens (feed, token) VALUES (?, ?) ON CONFLICT (feed) DO UPDATE SET token = excluded.token"
+	if _, err := db.GetDB().Exec(query, feed, token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeFeedToken disables a feed
+func RevokeFeedToken(feed string) error {
+	if !slices.Contains(Feeds, feed) {
+		return ErrUnknownFeed
+	}
+	_, err := db.GetDB().Exec("DELETE FROM feed_tokens WHERE feed = ?", feed)
+	return err
+}
@@ -0,0 +1,140 @@
+package taskdb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/models"
+)
+
+const (
+	// DefaultPageLimit is the page size used when the client doesn't ask for one
+	DefaultPageLimit = 50
+	// MaxPageLimit caps the page size a client can request
+	MaxPageLimit = 500
+)
+
+// ErrInvalidCursor is returned for a cursor that wasn't issued by listTasks
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects a window of a task list; an empty Cursor starts from the beginning
+type Page struct {
+	Limit  int
+	Cursor string
+}
+
+// TaskPage is one window of a task list
+type TaskPage struct {
+	Tasks      []models.Task
+	NextCursor string // empty on the last page
+	Total      int    // number of tasks matching the query across all pages
+}
+
+// cursor points at the last row of a page by its sort key and id
+type cursor struct {
+	Key any   `json:"k"`
+	ID  int64 `json:"id"`
+}
+
+func encodeCursor(c cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	// Sort keys are text or numbers; anything else can't have come from encodeCursor
+	switch c.Key.(type) {
+	case string, float64:
+		return c, nil
+	default:
+		return c, ErrInvalidCursor
+	}
+}
+
+// listTasks runs a task query ordered by keyExpr and id (keyset pagination).
+// from is the FROM/WHERE part shared by the page and the total count,
+// with scheduler aliased as s; snippetExpr fills models.Task.Snippet.
+func listTasks(from string, args []any, snippetExpr, keyExpr string, desc bool, page Page) (TaskPage, error) {
+	if page.Limit <= 0 {
+		page.Limit = DefaultPageLimit
+	}
+
+	var result TaskPage
+	if err := db.GetDB().QueryRow("SELECT count(*) "+from, args...).Scan(&result.Total); err != nil {
+		return TaskPage{}, err
+	}
+
+	query := "SELECT * FROM (SELECT " + taskColumns + ", " + snippetExpr + " AS snippet, " + keyExpr + " AS sort_key " + from + ")"
+	queryArgs := append([]any{}, args...)
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return TaskPage{}, err
+		}
+		if desc {
+			query += " WHERE sort_key < ? OR (sort_key = ? AND id < ?)"
+		} else {
+			query += " WHERE sort_key > ? OR (sort_key = ? AND id > ?)"
+		}
+		queryArgs = append(queryArgs, c.Key, c.Key, c.ID)
+	}
+	// One extra row tells whether there is a next page
+	if desc {
+		query += " ORDER BY sort_key DESC, id DESC LIMIT ?"
+	} else {
+		query += " ORDER BY sort_key, id LIMIT ?"
+	}
+	queryArgs = append(queryArgs, page.Limit+1)
+
+	rows, err := db.GetDB().Query(query, queryArgs...)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	var last cursor
+	for rows.Next() {
+		var next cursor
+		var snippet string
+		task, err := scanTask(rows, &snippet, &next.Key)
+		if err != nil {
+			return TaskPage{}, err
+		}
+		task.Snippet = highlightSnippet(snippet)
+		if len(tasks) == page.Limit {
+			if result.NextCursor, err = encodeCursor(last); err != nil {
+				return TaskPage{}, err
+			}
+			break
+		}
+		if next.ID, err = strconv.ParseInt(task.ID, 10, 64); err != nil {
+			return TaskPage{}, err
+		}
+		tasks = append(tasks, task)
+		last = next
+	}
+
+	if err := rows.Err(); err != nil {
+		return TaskPage{}, err
+	}
+	if err := attachDetails(tasks); err != nil {
+		return TaskPage{}, err
+	}
+	result.Tasks = tasks
+	return result, nil
+}
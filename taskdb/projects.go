@@ -0,0 +1,160 @@
+package taskdb
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/filestore"
+	"github.com/deeramster/go_final_project/models"
+)
+
+// ErrProjectNotFound is returned for a project id that doesn't exist
+var ErrProjectNotFound = errors.New("project not found")
+
+// GetProjects returns all projects in their display order with task counts
+func GetProjects() ([]models.Project, error) {
+	query := `
+  SELECT p.id, p.name, p.color, p.position, count(s.id)
+  FROM projects p
+  LEFT JOIN scheduler s ON s.project_id = p.id
+  GROUP BY p.id
+  ORDER BY p.position, p.id
+ `
+	rows, err := db.GetDB().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		var project models.Project
+		if err := rows.Scan(&project.ID, &project.Name, &project.Color, &project.Position, &project.TaskCount); err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// GetProjectByID retrieves a project with its task count
+func GetProjectByID(projectID int64) (models.Project, error) {
+	query := `
+  SELECT p.id, p.name, p.color, p.position, (SELECT count(*) FROM scheduler WHERE project_id = p.id)
+  FROM projects p
+  WHERE p.id = ?
+ `
+	var project models.Project
+	err := db.GetDB().QueryRow(query, projectID).Scan(&project.ID, &project.Name, &project.Color, &project.Position, &project.TaskCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Project{}, ErrProjectNotFound
+	}
+	if err != nil {
+		return models.Project{}, err
+	}
+	return project, nil
+}
+
+// ProjectExists reports whether a project with the id exists
+func ProjectExists(projectID int64) (bool, error) {
+	var exists bool
+	err := db.GetDB().QueryRow("SELECT EXISTS (SELECT 1 FROM projects WHERE id = ?)", projectID).Scan(&exists)
+	return exists, err
+}
+
+// AddProject adds a project; a zero position puts it after the existing ones
+func AddProject(project models.Project) (int64, error) {
+	query := `
+  INSERT INTO projects (name, color, position)
+  VALUES (?, ?, CASE WHEN ? = 0 THEN (SELECT IFNULL(max(position), 0) + 1 FROM projects) ELSE ? END)
+ `
+	result, err := db.GetDB().Exec(query, project.Name, project.Color, project.Position, project.Position)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// UpdateProject updates the name, color and position of a project
+func UpdateProject(project models.Project) error {
+	query := "UPDATE projects SET name = ?, color = ?, position = ? WHERE id = ?"
+	result, err := db.GetDB().Exec(query, project.Name, project.Color, project.Position, project.ID)
+	if err != nil {
+		return err
+	}
+	return projectAffected(result)
+}
+
+// DeleteProject deletes a project together with its tasks when cascade is set,
+// otherwise its tasks are moved to the inbox
+func DeleteProject(projectID int64, cascade bool) error {
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var files []string
+	if cascade {
+		files, _, err = deleteTasks(tx, "project_id = ?", projectID)
+	} else {
+		_, err = tx.Exec("UPDATE scheduler SET project_id = NULL WHERE project_id = ?", projectID)
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM projects WHERE id = ?", projectID)
+	if err != nil {
+		return err
+	}
+	if err := projectAffected(result); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	filestore.Remove(files...)
+	return nil
+}
+
+// MoveTasks moves tasks to a project, or to the inbox when projectID is nil.
+// It returns the number of tasks moved.
+func MoveTasks(taskIDs []int64, projectID *int64) (int64, error) {
+	if len(taskIDs) == 0 {
+		return 0, nil
+	}
+	if projectID != nil {
+		exists, err := ProjectExists(*projectID)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, ErrProjectNotFound
+		}
+	}
+
+	args := []any{projectID}
+	for _, id := range taskIDs {
+		args = append(args, id)
+	}
+	query := "UPDATE scheduler SET project_id = ? WHERE id IN (" + placeholders(len(taskIDs)) + ")"
+	result, err := db.GetDB().Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// projectAffected turns a statement that touched no project into ErrProjectNotFound
+func projectAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
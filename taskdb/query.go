@@ -0,0 +1,369 @@
+package taskdb
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/searchlang"
+)
+
+// SortField is a column the task list can be ordered by
+type SortField string
+
+const (
+	SortDate     SortField = "date"
+	SortTitle    SortField = "title"
+	SortCreated  SortField = "created"
+	SortPriority SortField = "priority"
+)
+
+// priorityRank orders tasks without a priority after the lowest one
+const priorityRank = "IFNULL(NULLIF(s.priority, 0), 5)"
+
+// sortKeys maps sort fields to the SQL expression used as the keyset key.
+// Composite keys are concatenated as text, which sorts right because
+// dates are fixed-width and ranks are single digits.
+var sortKeys = map[SortField]string{
+	SortDate:     "s.date || ':' || " + priorityRank,
+	SortTitle:    "s.title",
+	SortCreated:  "s.id", // ids are AUTOINCREMENT, so they follow creation order
+	SortPriority: priorityRank + " || ':' || s.date",
+}
+
+// sortKeysDesc overrides keys whose tie-breaker keeps its direction when descending:
+// the latest day first, but still the most urgent task of the day first
+var sortKeysDesc = map[SortField]string{
+	SortDate: "s.date || ':' || (6 - " + priorityRank + ")",
+}
+
+// ValidSortField reports whether the task list can be sorted by field
+func ValidSortField(field string) bool {
+	_, ok := sortKeys[SortField(field)]
+	return ok
+}
+
+// Priority levels: 1 is the most urgent, 0 means no priority
+const (
+	MinPriority = 1
+	MaxPriority = 4
+)
+
+// RepeatTypes are the rule prefixes understood by dateutil.NextDate
+var RepeatTypes = []string{"y", "d", "w", "m"}
+
+// TaskQuery composes filters and ordering for the task list.
+// Every filter is ANDed with the others.
+type TaskQuery struct {
+	conds []string
+	args  []any
+	fts   bool // joined with scheduler_fts for a full-text match
+	empty bool // the query can't match anything
+	sort  SortField
+	desc  bool
+	err   error // a filter that couldn't be built, returned by List
+}
+
+// NewTaskQuery returns a query over all tasks ordered by date
+func NewTaskQuery() *TaskQuery {
+	return &TaskQuery{}
+}
+
+func (q *TaskQuery) where(cond string, args ...any) *TaskQuery {
+	q.conds = append(q.conds, cond)
+	q.args = append(q.args, args...)
+	return q
+}
+
+// OnDate keeps tasks scheduled on date (YYYYMMDD)
+func (q *TaskQuery) OnDate(date string) *TaskQuery {
+	return q.where("s.date = ?", date)
+}
+
+// DateFrom keeps tasks scheduled on or after date (YYYYMMDD)
+func (q *TaskQuery) DateFrom(date string) *TaskQuery {
+	return q.where("s.date >= ?", date)
+}
+
+// DateTo keeps tasks scheduled on or before date (YYYYMMDD)
+func (q *TaskQuery) DateTo(date string) *TaskQuery {
+	return q.where("s.date <= ?", date)
+}
+
+// Overdue keeps tasks scheduled before today (YYYYMMDD)
+func (q *TaskQuery) Overdue(today string) *TaskQuery {
+	return q.where("s.date < ?", today)
+}
+
+// Recurring keeps recurring tasks, or one-off tasks when recurring is false
+func (q *TaskQuery) Recurring(recurring bool) *TaskQuery {
+	if recurring {
+		return q.where("s.repeat <> ''")
+	}
+	return q.where("(s.repeat = '' OR s.repeat IS NULL)")
+}
+
+// RepeatType keeps tasks whose rule is of the given type (y, d, w or m)
+func (q *TaskQuery) RepeatType(kind string) *TaskQuery {
+	if kind == "y" {
+		return q.where("s.repeat = 'y'")
+	}
+	return q.where("s.repeat LIKE ?", kind+" %")
+}
+
+// HasComment keeps tasks with a comment, or without one when has is false
+func (q *TaskQuery) HasComment(has bool) *TaskQuery {
+	if has {
+		return q.where("s.comment <> ''")
+	}
+	return q.where("(s.comment = '' OR s.comment IS NULL)")
+}
+
+// Project keeps tasks of a project
+func (q *TaskQuery) Project(projectID int64) *TaskQuery {
+	return q.where("s.project_id = ?", projectID)
+}
+
+// Inbox keeps tasks that belong to no project
+func (q *TaskQuery) Inbox() *TaskQuery {
+	return q.where("s.project_id IS NULL")
+}
+
+// Priority keeps tasks with one of the priority levels; 0 stands for no priority
+func (q *TaskQuery) Priority(levels ...int) *TaskQuery {
+	if len(levels) == 0 {
+		return q
+	}
+	args := make([]any, 0, len(levels))
+	for _, level := range levels {
+		args = append(args, level)
+	}
+	return q.where("s.priority IN ("+placeholders(len(levels))+")", args...)
+}
+
+// Ready keeps tasks with no open blockers, or only blocked ones when ready is false
+func (q *TaskQuery) Ready(ready bool) *TaskQuery {
+	if ready {
+		return q.where("NOT EXISTS (SELECT 1 FROM task_dependencies d WHERE d.task_id = s.id)")
+	}
+	return q.where("EXISTS (SELECT 1 FROM task_dependencies d WHERE d.task_id = s.id)")
+}
+
+// Tag keeps tasks carrying the tag
+func (q *TaskQuery) Tag(name string) *TaskQuery {
+	return q.where(tagCondition, NormalizeTag(name))
+}
+
+const tagCondition = "s.id IN (SELECT tt.task_id FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE t.name = ?)"
+
+// Search keeps tasks whose title or comment contains the text.
+// With FTS5 available results are ranked and carry a highlighted snippet.
+func (q *TaskQuery) Search(text string) *TaskQuery {
+	if !db.FTSEnabled() {
+		pattern := likePattern(text)
+		return q.where("(casefold(s.title) LIKE ? OR casefold(s.comment) LIKE ?)", pattern, pattern)
+	}
+
+	match := ftsQuery(text)
+	if match == "" {
+		q.empty = true
+		return q
+	}
+	q.fts = true
+	return q.where("scheduler_fts MATCH ?", match)
+}
+
+// likePattern builds a substring pattern for LIKE over casefold()-ed columns
+func likePattern(text string) string {
+	return "%" + strings.ToLower(text) + "%"
+}
+
+// Match keeps tasks matching a parsed search query. A query of bare words
+// is searched like Search so it stays ranked; anything else is compiled to SQL.
+// A node the compiler doesn't know makes List return an error.
+func (q *TaskQuery) Match(node searchlang.Node) *TaskQuery {
+	if words, ok := searchlang.PlainWords(node); ok {
+		return q.Search(strings.Join(words, " "))
+	}
+	cond, args, err := compileSearch(node)
+	if err != nil {
+		q.err = err
+		return q
+	}
+	return q.where(cond, args...)
+}
+
+// compileSearch turns a search query AST into a parameterized SQL condition
+func compileSearch(node searchlang.Node) (string, []any, error) {
+	switch n := node.(type) {
+	case searchlang.And:
+		return compileGroup(n.Nodes, " AND ")
+	case searchlang.Or:
+		return compileGroup(n.Nodes, " OR ")
+	case searchlang.Not:
+		cond, args, err := compileSearch(n.Node)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT " + cond, args, nil
+	case searchlang.Term:
+		cond, args := compileTerm(n)
+		return cond, args, nil
+	default:
+		return "", nil, fmt.Errorf("taskdb: unknown search node %T", node)
+	}
+}
+
+func compileGroup(nodes []searchlang.Node, op string) (string, []any, error) {
+	conds := make([]string, 0, len(nodes))
+	var args []any
+	for _, node := range nodes {
+		cond, nodeArgs, err := compileSearch(node)
+		if err != nil {
+			return "", nil, err
+		}
+		conds = append(conds, cond)
+		args = append(args, nodeArgs...)
+	}
+	return "(" + strings.Join(conds, op) + ")", args, nil
+}
+
+func compileTerm(term searchlang.Term) (string, []any) {
+	switch term.Field {
+	case searchlang.FieldBefore:
+		return "s.date < ?", []any{term.Value}
+	case searchlang.FieldAfter:
+		return "s.date > ?", []any{term.Value}
+	case searchlang.FieldDate:
+		return "s.date = ?", []any{term.Value}
+	case searchlang.FieldPriority:
+		return "s.priority = ?", []any{term.Value}
+	case searchlang.FieldTag:
+		return tagCondition, []any{NormalizeTag(term.Value)}
+	case searchlang.FieldRepeat:
+		if slices.Contains(RepeatTypes, term.Value) {
+			if term.Value == "y" {
+				return "s.repeat = 'y'", nil
+			}
+			return "s.repeat LIKE ?", []any{term.Value + " %"}
+		}
+		return "s.repeat = ?", []any{term.Value}
+	}
+
+	if !db.FTSEnabled() {
+		pattern := likePattern(term.Value)
+		switch term.Field {
+		case searchlang.FieldTitle:
+			return "casefold(s.title) LIKE ?", []any{pattern}
+		case searchlang.FieldComment:
+			return "casefold(IFNULL(s.comment, '')) LIKE ?", []any{pattern}
+		default:
+			return "(casefold(s.title) LIKE ? OR casefold(IFNULL(s.comment, '')) LIKE ?)", []any{pattern, pattern}
+		}
+	}
+
+	match := ftsQuery(term.Value)
+	if match == "" {
+		// Nothing searchable in the value, e.g. only punctuation
+		return "1", nil
+	}
+	if term.Phrase {
+		match = `"` + strings.Join(ftsWords(term.Value), " ") + `"`
+	}
+	if term.Field != searchlang.FieldText {
+		match = term.Field + " : (" + match + ")"
+	}
+	return "s.id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?)", []any{match}
+}
+
+// Sort orders the list by field; an empty field keeps the default order,
+// which is by date, or by rank for a text search
+func (q *TaskQuery) Sort(field SortField, desc bool) *TaskQuery {
+	q.sort = field
+	q.desc = desc
+	return q
+}
+
+// highlightSnippet turns an FTS5 snippet into HTML. The matches come marked
+// with control characters rather than tags, so the task text can be escaped
+// first and only the <mark> elements added here reach the client as markup.
+func highlightSnippet(snippet string) string {
+	if snippet == "" {
+		return ""
+	}
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>").Replace(snippet)
+}
+
+// List runs the query and returns one page of it
+func (q *TaskQuery) List(page Page) (TaskPage, error) {
+	if q.err != nil {
+		return TaskPage{}, q.err
+	}
+	if q.empty {
+		return TaskPage{Tasks: []models.Task{}}, nil
+	}
+
+	snippetExpr := "''"
+	from := "FROM scheduler s"
+	if q.fts {
+		snippetExpr = "snippet(scheduler_fts, -1, char(2), char(3), '…', 12)"
+		from += " JOIN scheduler_fts ON scheduler_fts.rowid = s.id"
+	}
+	if len(q.conds) > 0 {
+		from += " WHERE " + strings.Join(q.conds, " AND ")
+	}
+
+	field := q.sort
+	if field == "" {
+		field = SortDate
+	}
+	keyExpr := sortKeys[field]
+	if key, ok := sortKeysDesc[field]; ok && q.desc {
+		keyExpr = key
+	}
+	if q.sort == "" && q.fts {
+		keyExpr = "bm25(scheduler_fts)"
+	}
+	return listTasks(from, q.args, snippetExpr, keyExpr, q.desc, page)
+}
+
+// All runs the query and returns every matching task, for feeds and
+// exports that can't be paged
+func (q *TaskQuery) All() ([]models.Task, error) {
+	tasks := []models.Task{}
+	page := Page{Limit: MaxPageLimit}
+	for {
+		result, err := q.List(page)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, result.Tasks...)
+		if result.NextCursor == "" {
+			return tasks, nil
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+// ftsQuery turns free text into an FTS5 expression where every word
+// is a quoted prefix term, so user input can't inject FTS5 syntax
+func ftsQuery(search string) string {
+	words := ftsWords(search)
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// ftsWords splits text into the letter and digit runs FTS5 indexes
+func ftsWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
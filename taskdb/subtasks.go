@@ -0,0 +1,193 @@
+package taskdb
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/models"
+)
+
+var (
+	// ErrSubtaskNotFound is returned for a checklist item id that doesn't exist
+	ErrSubtaskNotFound = errors.New("subtask not found")
+	// ErrInvalidOrder is returned when a new order doesn't list exactly the task's items
+	ErrInvalidOrder = errors.New("order must list every subtask of the task once")
+)
+
+// GetSubtasks returns the checklist of a task in display order
+func GetSubtasks(taskID int) ([]models.Subtask, error) {
+	query := "SELECT id, task_id, title, done, position FROM subtasks WHERE task_id = ? ORDER BY position, id"
+	rows, err := db.GetDB().Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subtasks := []models.Subtask{}
+	for rows.Next() {
+		var subtask models.Subtask
+		if err := rows.Scan(&subtask.ID, &subtask.TaskID, &subtask.Title, &subtask.Done, &subtask.Position); err != nil {
+			return nil, err
+		}
+		subtasks = append(subtasks, subtask)
+	}
+	return subtasks, rows.Err()
+}
+
+// AddSubtask appends an item to the end of a task's checklist
+func AddSubtask(taskID int, title string) (int64, error) {
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+  INSERT INTO subtasks (task_id, title, position)
+  VALUES (?, ?, (SELECT IFNULL(max(position), 0) + 1 FROM subtasks WHERE task_id = ?))
+ `
+	result, err := tx.Exec(query, taskID, title, taskID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := bumpTask(tx, int64(taskID)); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// ToggleSubtask flips the done state of an item and returns it
+func ToggleSubtask(subtaskID int64) (models.Subtask, error) {
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return models.Subtask{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE subtasks SET done = NOT done WHERE id = ?", subtaskID); err != nil {
+		return models.Subtask{}, err
+	}
+
+	var subtask models.Subtask
+	query := "SELECT id, task_id, title, done, position FROM subtasks WHERE id = ?"
+	err = tx.QueryRow(query, subtaskID).Scan(&subtask.ID, &subtask.TaskID, &subtask.Title, &subtask.Done, &subtask.Position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Subtask{}, ErrSubtaskNotFound
+	}
+	if err != nil {
+		return models.Subtask{}, err
+	}
+	taskID, err := strconv.ParseInt(subtask.TaskID, 10, 64)
+	if err != nil {
+		return models.Subtask{}, err
+	}
+	if err := bumpTask(tx, taskID); err != nil {
+		return models.Subtask{}, err
+	}
+	return subtask, tx.Commit()
+}
+
+// DeleteSubtask removes an item from its checklist
+func DeleteSubtask(subtaskID int64) error {
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var taskID int64
+	err = tx.QueryRow("SELECT task_id FROM subtasks WHERE id = ?", subtaskID).Scan(&taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSubtaskNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM subtasks WHERE id = ?", subtaskID); err != nil {
+		return err
+	}
+	if err := bumpTask(tx, taskID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReorderSubtasks sets the checklist order of a task; order must list every item id once
+func ReorderSubtasks(taskID int, order []int64) error {
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	seen := make(map[int64]bool, len(order))
+	for _, id := range order {
+		if seen[id] {
+			return ErrInvalidOrder
+		}
+		seen[id] = true
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT count(*) FROM subtasks WHERE task_id = ?", taskID).Scan(&count); err != nil {
+		return err
+	}
+	if count != len(order) {
+		return ErrInvalidOrder
+	}
+
+	for position, id := range order {
+		result, err := tx.Exec("UPDATE subtasks SET position = ? WHERE id = ? AND task_id = ?", position+1, id, taskID)
+		if err != nil {
+			return err
+		}
+		// An id of another task's item leaves some item of this task unplaced
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrInvalidOrder
+		}
+	}
+	if err := bumpTask(tx, int64(taskID)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// attachProgress loads checklist progress of the given tasks in one query
+func attachProgress(tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index, args := taskIndex(tasks)
+	query := `
+  SELECT task_id, sum(done), count(*)
+  FROM subtasks
+  WHERE task_id IN (` + placeholders(len(args)) + `)
+  GROUP BY task_id
+ `
+	rows, err := db.GetDB().Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID string
+		var progress models.Progress
+		if err := rows.Scan(&taskID, &progress.Done, &progress.Total); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			tasks[i].Progress = &progress
+		}
+	}
+	return rows.Err()
+}
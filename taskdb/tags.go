@@ -0,0 +1,206 @@
+package taskdb
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/models"
+)
+
+var (
+	// ErrTagNotFound is returned when renaming or merging a tag nobody uses
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrTagExists is returned when renaming a tag to a name that is taken
+	ErrTagExists = errors.New("tag already exists")
+)
+
+// NormalizeTag returns the stored form of a tag name: trimmed and lower-cased
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// normalizeTags normalizes names, dropping empty ones and duplicates
+func normalizeTags(names []string) []string {
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		tag := NormalizeTag(name)
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// setTaskTags replaces the tags of a task
+func setTaskTags(tx *sql.Tx, taskID int64, names []string) error {
+	if _, err := tx.Exec("DELETE FROM task_tags WHERE task_id = ?", taskID); err != nil {
+		return err
+	}
+	for _, tag := range normalizeTags(names) {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+			return err
+		}
+		query := "INSERT INTO task_tags (task_id, tag_id) SELECT ?, id FROM tags WHERE name = ?"
+		if _, err := tx.Exec(query, taskID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachTags loads the tags of the given tasks in one query
+func attachTags(tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index, args := taskIndex(tasks)
+	query := `
+  SELECT tt.task_id, t.name
+  FROM task_tags tt
+  JOIN tags t ON t.id = tt.tag_id
+  WHERE tt.task_id IN (` + placeholders(len(args)) + `)
+  ORDER BY t.name
+ `
+	rows, err := db.GetDB().Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, name string
+		if err := rows.Scan(&taskID, &name); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			tasks[i].Tags = append(tasks[i].Tags, name)
+		}
+	}
+	return rows.Err()
+}
+
+// GetTags returns all tags in use with the number of tasks carrying each
+func GetTags() ([]models.Tag, error) {
+	query := `
+  SELECT t.name, count(tt.task_id)
+  FROM tags t
+  JOIN task_tags tt ON tt.tag_id = t.id
+  GROUP BY t.id
+  ORDER BY t.name
+ `
+	rows, err := db.GetDB().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// RenameTag renames a tag on every task carrying it
+func RenameTag(from, to string) error {
+	from, to = NormalizeTag(from), NormalizeTag(to)
+	if from == to {
+		return nil
+	}
+
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM tags WHERE name = ?)", to).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrTagExists
+	}
+
+	var tagID int64
+	err = tx.QueryRow("SELECT id FROM tags WHERE name = ?", from).Scan(&tagID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTagNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE tags SET name = ? WHERE id = ?", to, tagID); err != nil {
+		return err
+	}
+	if err := bumpTagged(tx, tagID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MergeTags moves every task tagged with one of the sources to the target tag
+// and removes the sources. The target is created if it doesn't exist yet.
+func MergeTags(sources []string, target string) error {
+	target = NormalizeTag(target)
+
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", target); err != nil {
+		return err
+	}
+	for _, source := range normalizeTags(sources) {
+		if source == target {
+			continue
+		}
+		var sourceID int64
+		err := tx.QueryRow("SELECT id FROM tags WHERE name = ?", source).Scan(&sourceID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTagNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := bumpTagged(tx, sourceID); err != nil {
+			return err
+		}
+		query := `
+  INSERT OR IGNORE INTO task_tags (task_id, tag_id)
+  SELECT task_id, (SELECT id FROM tags WHERE name = ?) FROM task_tags WHERE tag_id = ?
+ `
+		if _, err := tx.Exec(query, target, sourceID); err != nil {
+			return err
+		}
+		// Dropping the last link removes the source tag itself
+		if _, err := tx.Exec("DELETE FROM task_tags WHERE tag_id = ?", sourceID); err != nil {
+			return err
+		}
+	}
+
+	// A target nobody ended up using would never be pruned by the trigger
+	_, err = tx.Exec("DELETE FROM tags WHERE name = ? AND NOT EXISTS (SELECT 1 FROM task_tags WHERE tag_id = tags.id)", target)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// bumpTagged bumps the version of every task carrying the tag, so that
+// ETags and CalDAV sync tokens see the changed tag list
+func bumpTagged(tx *sql.Tx, tagID int64) error {
+	_, err := tx.Exec("UPDATE scheduler SET version = version + 1 WHERE id IN (SELECT task_id FROM task_tags WHERE tag_id = ?)", tagID)
+	return err
+}
@@ -1,143 +1,308 @@
 package taskdb
 
 import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
 	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/filestore"
 	"github.com/deeramster/go_final_project/models"
 )
 
-const maxTasksReturned = 50
+var (
+	// ErrNotFound is returned when a task referenced by id doesn't exist
+	ErrNotFound = errors.New("task not found")
+	// ErrVersionMismatch is returned by conditional writes when the task has changed
+	ErrVersionMismatch = errors.New("task version mismatch")
+	// ErrAlreadyDone is returned by MarkTaskAsDone when the task has already
+	// been moved off the expected date
+	ErrAlreadyDone = errors.New("task has already been completed")
+)
+
+// taskColumns selects a task from scheduler aliased as s, in the order scanTask reads it
+const taskColumns = "s.id AS id, s.date AS date, s.title AS title, s.comment AS comment, s.repeat AS repeat, " +
+	"s.project_id AS project_id, s.priority AS priority, s.version AS version"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask reads a row selected with taskColumns followed by extra columns
+func scanTask(row rowScanner, extra ...any) (models.Task, error) {
+	var task models.Task
+	var projectID sql.NullInt64
+	var priority int
+	dest := append([]any{&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat, &projectID, &priority, &task.Version}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return models.Task{}, err
+	}
+	if projectID.Valid {
+		task.ProjectID = &projectID.Int64
+	}
+	if priority != 0 {
+		task.Priority = &priority
+	}
+	return task, nil
+}
 
-// AddTaskToDB adds a new task to the database
+// AddTaskToDB adds a new task with its tags to the database
 func AddTaskToDB(task models.Task) (int64, error) {
-	query := "INSERT INTO scheduler (title, date, comment, repeat) VALUES (?, ?, ?, ?)"
-	result, err := db.GetDB().Exec(query, task.Title, task.Date, task.Comment, task.Repeat)
+	tx, err := db.BeginWrite()
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
-}
+	defer tx.Rollback()
 
-// GetTasksFromDB retrieves tasks from the database
-func GetTasksFromDB() ([]models.Task, error) {
-	query := "SELECT id, date, title, comment, repeat FROM scheduler ORDER BY date LIMIT ?"
-	rows, err := db.GetDB().Query(query, maxTasksReturned)
+	id, err := insertTask(tx, task)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
+	return id, tx.Commit()
+}
 
-	var tasks []models.Task
-	for rows.Next() {
-		var task models.Task
-		if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
+// insertTask inserts a task with its tags inside tx
+func insertTask(tx *sql.Tx, task models.Task) (int64, error) {
+	query := "INSERT INTO scheduler (title, date, comment, repeat, project_id, priority) VALUES (?, ?, ?, ?, ?, IFNULL(?, 0))"
+	result, err := tx.Exec(query, task.Title, task.Date, task.Comment, task.Repeat, task.ProjectID, task.Priority)
+	if err != nil {
+		return 0, err
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
 	}
-	return tasks, nil
+	if err := setTaskTags(tx, id, task.Tags); err != nil {
+		return 0, err
+	}
+	return id, nil
 }
 
-// GetTaskByID retrieves a task by its ID
+// GetTasksFromDB retrieves one page of tasks ordered by date
+func GetTasksFromDB(page Page) (TaskPage, error) {
+	return NewTaskQuery().List(page)
+}
+
+// GetTaskByID retrieves a task with its tags by its ID
 func GetTaskByID(taskID int) (models.Task, error) {
-	query := "SELECT id, title, date, comment, repeat FROM scheduler WHERE id = ?"
-	var task models.Task
-	err := db.GetDB().QueryRow(query, taskID).Scan(&task.ID, &task.Title, &task.Date, &task.Comment, &task.Repeat)
+	query := "SELECT " + taskColumns + " FROM scheduler s WHERE s.id = ?"
+	task, err := scanTask(db.GetDB().QueryRow(query, taskID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Task{}, ErrNotFound
+	}
 	if err != nil {
 		return models.Task{}, err
 	}
-	return task, nil
+	tasks := []models.Task{task}
+	if err := attachDetails(tasks); err != nil {
+		return models.Task{}, err
+	}
+	return tasks[0], nil
 }
 
-// UpdateTaskInDB updates an existing task in the database
-func UpdateTaskInDB(task models.Task) error {
-	query := "UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?"
-	_, err := db.GetDB().Exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.ID)
+// bumpTask bumps the version of a task whose related rows changed, so that
+// ETags and CalDAV sync tokens see a change stored outside the task row
+func bumpTask(tx *sql.Tx, taskID int64) error {
+	_, err := tx.Exec("UPDATE scheduler SET version = version + 1 WHERE id = ?", taskID)
 	return err
 }
 
-// DeleteTaskFromDB deletes a task by its ID
-func DeleteTaskFromDB(taskID int) error {
-	query := "DELETE FROM scheduler WHERE id = ?"
-	_, err := db.GetDB().Exec(query, taskID)
-	return err
+// UpdateTaskInDB updates an existing task in the database and returns its new version,
+// or ErrNotFound if there is no such task. Tags, project and priority are changed
+// only when set in task. A non-zero task.Version makes the update conditional:
+// ErrVersionMismatch is returned when the stored task has been changed since
+// that version was read.
+func UpdateTaskInDB(task models.Task) (int64, error) {
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := strconv.ParseInt(task.ID, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+  UPDATE scheduler
+  SET date = ?, title = ?, comment = ?, repeat = ?,
+   project_id = COALESCE(?, project_id), priority = COALESCE(?, priority)
+  WHERE id = ? AND (? = 0 OR version = ?)
+ `
+	result, err := tx.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.ProjectID, task.Priority,
+		id, task.Version, task.Version)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, conflictError(tx, id, ErrVersionMismatch)
+	}
+
+	if task.Tags != nil {
+		if err := setTaskTags(tx, id, task.Tags); err != nil {
+			return 0, err
+		}
+	}
+
+	var version int64
+	if err := tx.QueryRow("SELECT version FROM scheduler WHERE id = ?", id).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
 }
 
-// MarkTaskAsDone deletes a task or updates it if it's recurring
-func MarkTaskAsDone(taskID int, nextDate string) error {
-	if nextDate == "" {
-		// Delete non-recurring task
-		query := "DELETE FROM scheduler WHERE id = ?"
-		_, err := db.GetDB().Exec(query, taskID)
+// DeleteTaskFromDB deletes a task by its ID together with its attachment files,
+// or returns ErrNotFound. A non-zero version makes the delete conditional,
+// as in UpdateTaskInDB.
+func DeleteTaskFromDB(taskID int, version int64) error {
+	tx, err := db.BeginWrite()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	files, deleted, err := deleteTasks(tx, "id = ? AND (? = 0 OR version = ?)", taskID, version, version)
+	if err != nil {
 		return err
-	} else {
-		// Update date for recurring task
-		query := "UPDATE scheduler SET date = ? WHERE id = ?"
-		_, err := db.GetDB().Exec(query, nextDate, taskID)
+	}
+	if deleted == 0 {
+		return conflictError(tx, int64(taskID), ErrVersionMismatch)
+	}
+	if err := tx.Commit(); err != nil {
 		return err
 	}
+	filestore.Remove(files...)
+	return nil
 }
 
-// SearchTasksByDate retrieves tasks by date
-func SearchTasksByDate(date string) ([]models.Task, error) {
-	query := `
-  SELECT id, date, title, comment, repeat
-  FROM scheduler
-  WHERE date = ?
-  ORDER BY date
-  LIMIT ?
- `
-	rows, err := db.GetDB().Query(query, date, maxTasksReturned)
+// conflictError explains why a write touched no rows: it returns
+// ErrNotFound when the task is gone and conflict when it has changed
+func conflictError(tx *sql.Tx, taskID int64, conflict error) error {
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM scheduler WHERE id = ?)", taskID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return conflict
+}
+
+// MarkTaskAsDone completes a task that is still scheduled on date: a one-off
+// task is deleted, a recurring one is moved to nextDate, its checklist is
+// started over and the tasks waiting for it are released. The date check and
+// the write are one statement, so of two concurrent completions only the
+// first one succeeds; the other one gets ErrAlreadyDone, or ErrNotFound once
+// a one-off task is gone.
+func MarkTaskAsDone(taskID int, date, nextDate string) error {
+	tx, err := db.BeginWrite()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var tasks []models.Task
-	for rows.Next() {
-		var task models.Task
-		if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
-			return nil, err
+	if nextDate == "" {
+		// Delete non-recurring task
+		files, deleted, err := deleteTasks(tx, "id = ? AND date = ?", taskID, date)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return conflictError(tx, int64(taskID), ErrAlreadyDone)
 		}
-		tasks = append(tasks, task)
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		filestore.Remove(files...)
+		return nil
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	// Update date for recurring task and start its checklist over
+	result, err := tx.Exec("UPDATE scheduler SET date = ? WHERE id = ? AND date = ?", nextDate, taskID, date)
+	if err != nil {
+		return err
 	}
-	return tasks, nil
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return conflictError(tx, int64(taskID), ErrAlreadyDone)
+	}
+	if _, err := tx.Exec("UPDATE subtasks SET done = 0 WHERE task_id = ?", taskID); err != nil {
+		return err
+	}
+	// The occurrence they waited for is done; the trigger only covers deleted tasks
+	if _, err := tx.Exec("UPDATE scheduler SET version = version + 1 WHERE id IN (SELECT task_id FROM task_dependencies WHERE blocker_id = ?)", taskID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM task_dependencies WHERE blocker_id = ?", taskID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// SearchTasksByText searches for tasks by text in title or comment
-func SearchTasksByText(search string) ([]models.Task, error) {
-	searchPattern := "%" + search + "%"
-	query := `
-  SELECT id, date, title, comment, repeat
-  FROM scheduler
-  WHERE title LIKE ? OR comment LIKE ?
-  ORDER BY date
-  LIMIT ?
- `
-	rows, err := db.GetDB().Query(query, searchPattern, searchPattern, maxTasksReturned)
+// deleteTasks deletes the tasks matching where and returns the stored names
+// of their attachments and the number of deleted tasks; the caller removes
+// the files once tx is committed
+func deleteTasks(tx *sql.Tx, where string, args ...any) ([]string, int64, error) {
+	rows, err := tx.Query("SELECT stored_name FROM attachments WHERE task_id IN (SELECT id FROM scheduler WHERE "+where+")", args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var tasks []models.Task
+	var files []string
 	for rows.Next() {
-		var task models.Task
-		if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
-			return nil, err
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, 0, err
 		}
-		tasks = append(tasks, task)
+		files = append(files, name)
 	}
-
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	if err := bumpLinked(tx, where, args...); err != nil {
+		return nil, 0, err
+	}
+	result, err := tx.Exec("DELETE FROM scheduler WHERE "+where, args...)
+	if err != nil {
+		return nil, 0, err
 	}
-	return tasks, nil
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return nil, 0, err
+	}
+	return files, deleted, nil
+}
+
+// attachDetails loads tags, checklist progress and dependencies of the given tasks
+func attachDetails(tasks []models.Task) error {
+	if err := attachTags(tasks); err != nil {
+		return err
+	}
+	if err := attachProgress(tasks); err != nil {
+		return err
+	}
+	return attachDependencies(tasks)
+}
+
+// taskIndex maps task ids to their position in tasks and returns the ids as query args
+func taskIndex(tasks []models.Task) (map[string]int, []any) {
+	index := make(map[string]int, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for i, task := range tasks {
+		index[task.ID] = i
+		args = append(args, task.ID)
+	}
+	return index, args
+}
+
+// placeholders returns n comma-separated query placeholders
+func placeholders(n int) string {
+	return "?" + strings.Repeat(", ?", n-1)
 }
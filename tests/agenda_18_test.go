@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOccurrences(t *testing.T) {
+	tbl := []struct {
+		date   string
+		repeat string
+		dates  []string
+	}{
+		{"20300105", "", []string{"20300105"}},
+		{"20291229", "", nil},
+		{"20300101", "d 3", []string{"20300101", "20300104", "20300107"}},
+		// 7 января 2030 года — понедельник
+		{"20291201", "w 1,5", []string{"20291231", "20300104", "20300107"}},
+		{"20291215", "m -1,5", []string{"20291231", "20300105"}},
+		{"20290103", "y", []string{"20300103"}},
+	}
+	for _, v := range tbl {
+		dates, err := dateutil.Occurrences(v.date, v.repeat, "20291230", "20300107")
+		assert.NoError(t, err)
+		assert.Equal(t, v.dates, dates, v.repeat)
+	}
+
+	_, err := dateutil.Occurrences("20300101", "m 40", "20300101", "20300107")
+	assert.Error(t, err)
+}
+
+func TestAgenda(t *testing.T) {
+	id := addTask(t, task{
+		date:    "20300107",
+		title:   "Планёрка для повестки",
+		comment: "первая строка\nвторая строка",
+		repeat:  "d 2",
+	})
+
+	body, err := requestJSON("api/agenda.md?from=20300107&to=20300110", nil, http.MethodGet)
+	assert.NoError(t, err)
+	text := string(body)
+	assert.Contains(t, text, "# Повестка: 07.01.2030 — 10.01.2030")
+	assert.Contains(t, text, "## Понедельник, 07.01.2030\n")
+	assert.Contains(t, text, "- [ ] Планёрка для повестки _(раз в 2 дн.)_\n  первая строка\n  вторая строка\n")
+	assert.Contains(t, text, "## Среда, 09.01.2030\n")
+	assert.Contains(t, text, "- [ ] Планёрка для повестки _(будущее повторение, раз в 2 дн.)_")
+	// Во вторник повторения нет
+	_, tuesday, _ := strings.Cut(text, "## Вторник, 08.01.2030\n")
+	tuesday, _, _ = strings.Cut(tuesday, "## Среда")
+	assert.NotContains(t, tuesday, "Планёрка для повестки")
+
+	// Разметка в заголовке экранируется и показывается как написана
+	marked := addTask(t, task{
+		date:    "20300108",
+		title:   "[x] *важно* #1 _срочно_ <b>`код`</b> a|b",
+		comment: "# не заголовок\n- не пункт\n  12. не номер\n---\n<script>x</script> [ссылка](http://a)",
+	})
+	body, err = requestJSON("api/agenda.md?from=20300108&to=20300108", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "- [ ] \\[x\\] \\*важно\\* \\#1 \\_срочно\\_ \\<b\\>\\`код\\`\\</b\\> a\\|b\n")
+	// В комментарии экранируется и разметка в начале строк
+	assert.Contains(t, string(body), "\n  \\# не заголовок\n  \\- не пункт\n  12\\. не номер\n  \\---\n"+
+		"  \\<script\\>x\\</script\\> \\[ссылка\\](http://a)\n")
+	requestStatus("api/task?id="+marked, http.MethodDelete)
+
+	status, err := requestStatus("api/agenda.md?from=20300110&to=20300107", http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, err = requestStatus("api/task?id="+id, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	body, err = requestJSON("api/agenda.md?from=20300107&to=20300110", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "Планёрка для повестки")
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeRepeat(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		text   string
+	}{
+		{"", ""},
+		{"y", "каждый год"},
+		{"d 1", "каждый день"},
+		{"d 3", "раз в 3 дн."},
+		{"w 1,3", "по дням недели: пн, ср"},
+		{"m 1,-1", "по числам месяца: 1, последнее"},
+		{"m 15 1,12", "по числам месяца: 15; месяцы: янв, дек"},
+		{"k 5", "k 5"},
+	}
+	for _, v := range tbl {
+		assert.Equal(t, v.text, dateutil.DescribeRepeat(v.repeat), v.repeat)
+	}
+}
+
+func TestAtomFeed(t *testing.T) {
+	now := time.Now()
+	overdue := addTask(t, task{
+		date:    now.AddDate(0, 0, -3).Format(`20060102`),
+		title:   "Просроченная для ленты",
+		comment: "Не забыть",
+	})
+	upcoming := addTask(t, task{
+		date:   now.AddDate(0, 0, 2).Format(`20060102`),
+		title:  "Скоро для ленты",
+		repeat: "d 5",
+	})
+	later := addTask(t, task{
+		date:  now.AddDate(0, 0, 30).Format(`20060102`),
+		title: "Нескоро для ленты",
+	})
+
+	ret, err := postJSON("api/feeds?feed=atom", nil, http.MethodPost)
+	assert.NoError(t, err)
+	url, _ := ret["url"].(string)
+	assert.True(t, strings.HasPrefix(url, "/api/tasks.atom?token="), url)
+
+	resp, err := http.Get(getURL(strings.TrimPrefix(url, "/")))
+	if assert.NoError(t, err) {
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(resp.Body)
+		resp.Body.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "application/atom+xml")
+		body := buf.String()
+		assert.Contains(t, body, "Просроченная для ленты")
+		assert.Contains(t, body, "(просрочено)")
+		assert.Contains(t, body, "Скоро для ленты")
+		assert.Contains(t, body, "Повторение: раз в 5 дн.")
+		assert.NotContains(t, body, "Нескоро для ленты")
+	}
+
+	// С большим горизонтом видны и дальние задачи
+	resp, err = http.Get(getURL(strings.TrimPrefix(url, "/") + "&days=60"))
+	if assert.NoError(t, err) {
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(resp.Body)
+		resp.Body.Close()
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "Нескоро для ленты")
+	}
+
+	resp, err = http.Get(getURL(strings.TrimPrefix(url, "/") + "&days=-1"))
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+
+	status, err := requestStatus("api/feeds?feed=atom", http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	for _, id := range []string{overdue, upcoming, later} {
+		status, err = requestStatus("api/task?id="+id, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+	}
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/handlers"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadRequest собирает multipart-запрос с файлом в поле field
+func uploadRequest(t *testing.T, url, field, name, content string) *http.Request {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	assert.NoError(t, mw.WriteField("comment", "не файл"))
+	part, err := mw.CreateFormFile(field, name)
+	assert.NoError(t, err)
+	_, err = io.WriteString(part, content)
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, url, &buf)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestAttachments(t *testing.T) {
+	localDB(t)
+	keepConfig(t)
+	dir := t.TempDir()
+	config.AppConfig.AttachmentsDir = dir
+	config.AppConfig.MaxAttachmentSize = 64
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/task/attachments", handlers.HandleTaskAttachments)
+	mux.HandleFunc("/api/attachment", handlers.HandleAttachment)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	id, err := taskdb.AddTaskToDB(models.Task{Date: "20300101", Title: "С вложениями"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	taskURL := fmt.Sprintf("%s/api/task/attachments?id=%d", srv.URL, id)
+
+	// Добавление и удаление вложения меняет версию задачи
+	version := func() int64 {
+		task, err := taskdb.GetTaskByID(int(id))
+		assert.NoError(t, err)
+		return task.Version
+	}
+	before := version()
+
+	do := func(req *http.Request) (*http.Response, string) {
+		return doRequest(t, srv.Client(), req)
+	}
+
+	resp, body := do(uploadRequest(t, taskURL, "file", "../../план.txt", "первая строка\n"))
+	if !assert.Equal(t, http.StatusOK, resp.StatusCode, body) {
+		return
+	}
+	var created map[string]int64
+	assert.NoError(t, json.Unmarshal([]byte(body), &created))
+	attachmentURL := fmt.Sprintf("%s/api/attachment?id=%d", srv.URL, created["id"])
+	assert.NotEqual(t, before, version())
+
+	// Имя файла клиента не попадает на диск, в хранилище лежит один файл со случайным именем
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	req, _ := http.NewRequest(http.MethodGet, taskURL, nil)
+	_, body = do(req)
+	var list map[string][]models.Attachment
+	assert.NoError(t, json.Unmarshal([]byte(body), &list))
+	if assert.Len(t, list["attachments"], 1) {
+		a := list["attachments"][0]
+		assert.Equal(t, "план.txt", a.Name)
+		assert.Equal(t, int64(len("первая строка\n")), a.Size)
+		assert.Contains(t, a.ContentType, "text/plain")
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, attachmentURL, nil)
+	resp, body = do(req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "первая строка\n", body)
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
+
+	// Файл ровно по лимиту проходит, на байт больше — 413
+	resp, body = do(uploadRequest(t, taskURL, "file", "ровно.bin", strings.Repeat("x", 64)))
+	assert.Equal(t, http.StatusOK, resp.StatusCode, body)
+	resp, body = do(uploadRequest(t, taskURL, "file", "большой.bin", strings.Repeat("x", 65)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, body)
+	files, err = os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	resp, _ = do(uploadRequest(t, taskURL, "other", "поле.txt", "текст"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	req, _ = http.NewRequest(http.MethodPost, taskURL, strings.NewReader("не форма"))
+	resp, _ = do(req)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = do(uploadRequest(t, srv.URL+"/api/task/attachments?id=999999", "file", "ничей.txt", "текст"))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	before = version()
+	req, _ = http.NewRequest(http.MethodDelete, attachmentURL, nil)
+	resp, _ = do(req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, before, version())
+	resp, _ = do(req)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	req, _ = http.NewRequest(http.MethodGet, attachmentURL, nil)
+	resp, _ = do(req)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Файлы удаляются вместе с задачей
+	assert.NoError(t, taskdb.DeleteTaskFromDB(int(id), 0))
+	files, err = os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+	attachments, err := taskdb.GetAttachments(int(id))
+	assert.NoError(t, err)
+	assert.Empty(t, attachments)
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/deeramster/go_final_project/autobackup"
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+	"github.com/stretchr/testify/assert"
+)
+
+// backupName возвращает имя файла копии, снятой в момент created
+func backupName(created time.Time) string {
+	return "scheduler-" + created.Format("20060102-150405") + ".db.gz"
+}
+
+// useBackupDir переключает каталог копий и настройки расписания на время теста
+func useBackupDir(t *testing.T, interval time.Duration, keep int) string {
+	localDB(t)
+	keepConfig(t)
+	dir := t.TempDir()
+	config.AppConfig.BackupDir = dir
+	config.AppConfig.BackupInterval = interval
+	config.AppConfig.BackupKeep = keep
+	return dir
+}
+
+func TestAutoBackupCreate(t *testing.T) {
+	dir := useBackupDir(t, time.Hour, 3)
+	id, err := taskdb.AddTaskToDB(models.Task{Date: "20300101", Title: "В резервной копии"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer taskdb.DeleteTaskFromDB(int(id), 0)
+
+	// Старые копии и посторонний файл в каталоге
+	now := time.Now()
+	for _, age := range []time.Duration{24, 48, 72, 96} {
+		name := backupName(now.Add(-age * time.Hour))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("старая копия"), 0o644))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("не копия"), 0o644))
+
+	name, err := autobackup.Create(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Остаются три самые свежие копии, чужие файлы не трогаются
+	files, err := autobackup.List()
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{name, backupName(now.Add(-24 * time.Hour)), backupName(now.Add(-48 * time.Hour))}, names)
+	_, err = os.Stat(filepath.Join(dir, "notes.txt"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, backupName(now.Add(-96*time.Hour))))
+	assert.True(t, os.IsNotExist(err))
+
+	// Копия сжата gzip и распаковывается в целую базу с задачами
+	in, err := os.Open(filepath.Join(dir, name))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	if !assert.NoError(t, err) {
+		return
+	}
+	data, err := io.ReadAll(zr)
+	assert.NoError(t, err)
+	assert.Less(t, int64(0), files[0].Size)
+	assert.Less(t, files[0].Size, int64(len(data)))
+
+	restored := filepath.Join(t.TempDir(), "restored.db")
+	assert.NoError(t, os.WriteFile(restored, data, 0o644))
+	assert.NoError(t, db.Verify(restored))
+	conn, err := sql.Open("sqlite3", restored)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	var title string
+	assert.NoError(t, conn.QueryRow("SELECT title FROM scheduler WHERE id = ?", id).Scan(&title))
+	assert.Equal(t, "В резервной копии", title)
+
+	// Проверка целостности отвергает испорченные и посторонние файлы
+	for name, content := range map[string][]byte{"broken.db": data[:len(data)/2], "text.db": []byte("просто текст")} {
+		path := filepath.Join(t.TempDir(), name)
+		assert.NoError(t, os.WriteFile(path, content, 0o644))
+		assert.ErrorIs(t, db.Verify(path), db.ErrInvalidBackup, name)
+	}
+}
+
+// waitBackup ждёт, пока расписание снимет копию
+func waitBackup(t *testing.T) autobackup.Status {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := autobackup.GetStatus()
+		if status.LastSuccess != nil || status.LastError != "" || time.Now().After(deadline) {
+			return status
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestAutoBackupSchedule(t *testing.T) {
+	useBackupDir(t, time.Hour, 2)
+
+	// Без копий первая снимается сразу после запуска
+	autobackup.Start()
+	status := waitBackup(t)
+	autobackup.Stop()
+	assert.Empty(t, status.LastError)
+	if !assert.NotNil(t, status.LastSuccess) {
+		return
+	}
+	assert.NotEmpty(t, status.LastFile)
+
+	// После перезапуска следующая копия ждёт интервал от самой свежей
+	autobackup.Start()
+	status = autobackup.GetStatus()
+	autobackup.Stop()
+	assert.Nil(t, status.LastRun)
+	if assert.NotNil(t, status.NextRun) {
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *status.NextRun, time.Minute)
+	}
+
+	// Копия старше интервала означает, что очередная уже просрочена
+	dir := config.AppConfig.BackupDir
+	files, err := autobackup.List()
+	assert.NoError(t, err)
+	for _, f := range files {
+		assert.NoError(t, os.Remove(filepath.Join(dir, f.Name)))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, backupName(time.Now().Add(-2*time.Hour))), []byte("старая копия"), 0o644))
+	autobackup.Start()
+	status = waitBackup(t)
+	autobackup.Stop()
+	assert.NotNil(t, status.LastSuccess)
+}
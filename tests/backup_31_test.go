@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deeramster/go_final_project/auth"
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/handlers"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupRestore(t *testing.T) {
+	localDB(t)
+	keepConfig(t)
+	config.AppConfig.AdminToken = ""
+	config.AppConfig.MaxRestoreSize = 1 << 20
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/admin/backup", auth.AdminMiddleware(handlers.HandleBackup))
+	mux.HandleFunc("/api/admin/restore", auth.AdminMiddleware(handlers.HandleRestore))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	do := func(req *http.Request, token string) (*http.Response, string) {
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return doRequest(t, srv.Client(), req)
+	}
+	backupRequest := func() *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/admin/backup", nil)
+		return req
+	}
+
+	// Без TODO_ADMIN_TOKEN админ-API выключен
+	resp, _ := do(backupRequest(), "токен")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	config.AppConfig.AdminToken = "admin-secret"
+	resp, _ = do(backupRequest(), "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, _ = do(backupRequest(), "чужой")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	kept, err := taskdb.AddTaskToDB(models.Task{Date: "20300101", Title: "Из снимка"})
+	assert.NoError(t, err)
+	resp, backup := do(backupRequest(), "admin-secret")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(backup, "SQLite format 3\x00"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), ".db")
+
+	later, err := taskdb.AddTaskToDB(models.Task{Date: "20300101", Title: "После снимка"})
+	assert.NoError(t, err)
+	assert.NoError(t, taskdb.DeleteTaskFromDB(int(kept), 0))
+
+	resp, body := do(uploadRequest(t, srv.URL+"/api/admin/restore", "file", "backup.db", backup), "admin-secret")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, body)
+	_, err = taskdb.GetTaskByID(int(kept))
+	assert.NoError(t, err)
+	_, err = taskdb.GetTaskByID(int(later))
+	assert.ErrorIs(t, err, taskdb.ErrNotFound)
+
+	// Файл, который не является базой, и база без таблицы задач не восстанавливаются
+	path := filepath.Join(t.TempDir(), "other.db")
+	other, err := sql.Open("sqlite3", path)
+	assert.NoError(t, err)
+	_, err = other.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, text TEXT)")
+	assert.NoError(t, err)
+	other.Close()
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	for name, content := range map[string]string{"notes.txt": "просто текст, а не база", "other.db": string(data)} {
+		resp, body = do(uploadRequest(t, srv.URL+"/api/admin/restore", "file", name, content), "admin-secret")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, name)
+		assert.Contains(t, body, "not a valid scheduler database", name)
+	}
+	_, err = taskdb.GetTaskByID(int(kept))
+	assert.NoError(t, err)
+
+	resp, _ = do(uploadRequest(t, srv.URL+"/api/admin/restore", "backup", "backup.db", backup), "admin-secret")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	config.AppConfig.MaxRestoreSize = int64(len(backup) - 1)
+	resp, _ = do(uploadRequest(t, srv.URL+"/api/admin/restore", "file", "backup.db", backup), "admin-secret")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	assert.NoError(t, taskdb.DeleteTaskFromDB(int(kept), 0))
+}
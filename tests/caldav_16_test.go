@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/deeramster/go_final_project/auth"
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/handlers"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+	"github.com/stretchr/testify/assert"
+)
+
+// calDAVServer поднимает CalDAV в процессе теста на отдельной базе, чтобы не трогать задачи сервера
+func calDAVServer(t *testing.T) *httptest.Server {
+	localDB(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/caldav/", handlers.HandleCalDAV)
+	return httptest.NewServer(mux)
+}
+
+func calDAVRequest(t *testing.T, srv *httptest.Server, method, path, body string, header map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp, string(data)
+}
+
+func calDAVSyncToken(t *testing.T, body string) string {
+	_, rest, ok := strings.Cut(body, "<d:sync-token>")
+	if !assert.True(t, ok, body) {
+		return ""
+	}
+	token, _, _ := strings.Cut(rest, "</d:sync-token>")
+	return token
+}
+
+func TestCalDAV(t *testing.T) {
+	srv := calDAVServer(t)
+	defer srv.Close()
+
+	resp, body := calDAVRequest(t, srv, "PROPFIND", "/caldav/tasks/", `<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:resourcetype/><c:supported-calendar-component-set/><d:unknown/></d:prop>
+</d:propfind>`, map[string]string{"Depth": "0"})
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.Contains(t, body, "<c:calendar/>")
+	assert.Contains(t, body, `<c:comp name="VTODO"/>`)
+	assert.Contains(t, body, "<d:unknown/></d:prop><d:status>HTTP/1.1 404 Not Found")
+
+	syncReport := func(token string) (*http.Response, string) {
+		return calDAVRequest(t, srv, "REPORT", "/caldav/tasks/", `<d:sync-collection xmlns:d="DAV:">
+  <d:sync-token>`+token+`</d:sync-token><d:prop><d:getetag/></d:prop>
+</d:sync-collection>`, nil)
+	}
+	_, body = syncReport("")
+	token := calDAVSyncToken(t, body)
+
+	todo := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:phone-1\r\n" +
+		"SUMMARY:Купить хлеб\r\nDUE;VALUE=DATE:20300107\r\nRRULE:FREQ=WEEKLY\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	resp, _ = calDAVRequest(t, srv, http.MethodPut, "/caldav/tasks/phone-1.ics", todo, map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	// Сохранённая задача отличается от присланной, поэтому ETag приходит только с GET
+	assert.Empty(t, resp.Header.Get("ETag"))
+
+	// Повторное создание под тем же именем запрещено
+	resp, _ = calDAVRequest(t, srv, http.MethodPut, "/caldav/tasks/phone-1.ics", todo, map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp, body = calDAVRequest(t, srv, http.MethodGet, "/caldav/tasks/phone-1.ics", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Contains(t, body, "UID:phone-1")
+	assert.Contains(t, body, "SUMMARY:Купить хлеб")
+	assert.Contains(t, body, "RRULE:FREQ=WEEKLY;BYDAY=MO")
+
+	// Изменения после токена: только новая задача
+	_, body = syncReport(token)
+	assert.Contains(t, body, "/caldav/tasks/phone-1.ics")
+	assert.Contains(t, body, etag)
+	token = calDAVSyncToken(t, body)
+
+	// Устаревший ETag не даёт затереть чужие правки
+	changed := strings.Replace(todo, "Купить хлеб", "Купить хлеб и молоко", 1)
+	resp, _ = calDAVRequest(t, srv, http.MethodPut, "/caldav/tasks/phone-1.ics", changed, map[string]string{"If-Match": `"999"`})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp, _ = calDAVRequest(t, srv, http.MethodPut, "/caldav/tasks/phone-1.ics", changed, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("ETag"))
+	resp, _ = calDAVRequest(t, srv, http.MethodGet, "/caldav/tasks/phone-1.ics", "", nil)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+
+	// Задачу с незавершённой блокирующей задачей выполнить нельзя
+	task, err := taskdb.FindCalDAVTask("phone-1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	taskID, _ := strconv.ParseInt(task.ID, 10, 64)
+	blocker, err := taskdb.AddTaskToDB(models.Task{Date: "20300101", Title: "Найти кошелёк"})
+	assert.NoError(t, err)
+	assert.NoError(t, taskdb.AddDependency(taskID, blocker))
+	done := strings.Replace(changed, "END:VTODO", "STATUS:COMPLETED\r\nEND:VTODO", 1)
+	resp, body = calDAVRequest(t, srv, http.MethodPut, "/caldav/tasks/phone-1.ics", done, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Contains(t, body, "blocked_by")
+	_, body = calDAVRequest(t, srv, http.MethodGet, "/caldav/tasks/phone-1.ics", "", nil)
+	assert.Contains(t, body, "DUE;VALUE=DATE:20300107")
+	assert.NoError(t, taskdb.DeleteTaskFromDB(int(blocker), 0))
+
+	// Выполненная повторяющаяся задача переносится на следующую дату
+	resp, _ = calDAVRequest(t, srv, http.MethodPut, "/caldav/tasks/phone-1.ics", done, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("ETag"))
+	_, body = calDAVRequest(t, srv, http.MethodGet, "/caldav/tasks/phone-1.ics", "", nil)
+	assert.Contains(t, body, "Купить хлеб и молоко")
+	assert.NotContains(t, body, "DUE;VALUE=DATE:20300107")
+
+	_, body = calDAVRequest(t, srv, "REPORT", "/caldav/tasks/", `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <d:href>/caldav/tasks/phone-1.ics</d:href><d:href>/caldav/tasks/missing.ics</d:href>
+</c:calendar-multiget>`, nil)
+	assert.Contains(t, body, "SUMMARY:Купить хлеб и молоко")
+	assert.Contains(t, body, "<d:href>/caldav/tasks/missing.ics</d:href><d:status>HTTP/1.1 404 Not Found")
+
+	resp, _ = calDAVRequest(t, srv, http.MethodDelete, "/caldav/tasks/phone-1.ics", "", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = calDAVRequest(t, srv, http.MethodGet, "/caldav/tasks/phone-1.ics", "", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Удалённая задача приходит в синхронизации со статусом 404
+	_, body = syncReport(token)
+	assert.Contains(t, body, "<d:href>/caldav/tasks/phone-1.ics</d:href><d:status>HTTP/1.1 404 Not Found")
+
+	resp, _ = syncReport("urn:scheduler:sync:999999")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestCalDAVAuth(t *testing.T) {
+	localDB(t)
+	keepConfig(t)
+	config.AppConfig.Password = "пароль приложения"
+	srv := httptest.NewServer(auth.FeedMiddleware("caldav", handlers.HandleCalDAV))
+	defer srv.Close()
+
+	token, err := taskdb.RotateFeedToken("caldav")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer taskdb.RevokeFeedToken("caldav")
+
+	propfind := func(query, user, pass string) *http.Response {
+		req, err := http.NewRequest("PROPFIND", srv.URL+"/caldav/tasks/"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Depth", "0")
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		resp, _ := doRequest(t, srv.Client(), req)
+		return resp
+	}
+
+	// Без пароля клиент получает приглашение к Basic-авторизации
+	resp := propfind("", "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+
+	// Подходят и токен CalDAV, и пароль приложения с любым именем пользователя
+	assert.Equal(t, http.StatusMultiStatus, propfind("", "phone", token).StatusCode)
+	assert.Equal(t, http.StatusMultiStatus, propfind("", "phone", "пароль приложения").StatusCode)
+	assert.Equal(t, http.StatusMultiStatus, propfind("?token="+token, "", "").StatusCode)
+
+	// Неверный пароль и пароль приложения в адресе не принимаются
+	assert.Equal(t, http.StatusUnauthorized, propfind("", "phone", "пароль").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, propfind("?token="+url.QueryEscape("пароль приложения"), "", "").StatusCode)
+}
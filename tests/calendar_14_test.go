@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deeramster/go_final_project/ical"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRRule(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		rule   string
+	}{
+		{"y", "FREQ=YEARLY"},
+		{"d 1", "FREQ=DAILY"},
+		{"d 14", "FREQ=DAILY;INTERVAL=14"},
+		{"w 1,3,7", "FREQ=WEEKLY;BYDAY=MO,WE,SU"},
+		{"m 1,15", "FREQ=MONTHLY;BYMONTHDAY=1,15"},
+		{"m -1", "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{"m 10 1,6", "FREQ=MONTHLY;BYMONTHDAY=10;BYMONTH=1,6"},
+	}
+	for _, v := range tbl {
+		rule, ok := ical.RRule(v.repeat)
+		assert.True(t, ok, v.repeat)
+		assert.Equal(t, v.rule, rule, v.repeat)
+	}
+
+	for _, repeat := range []string{"", "d 500", "w 8", "x"} {
+		_, ok := ical.RRule(repeat)
+		assert.False(t, ok, repeat)
+	}
+}
+
+func TestICSEncode(t *testing.T) {
+	priority := 1
+	task := models.Task{
+		ID:       "42",
+		Date:     "20300107",
+		Title:    "Созвон; план, итоги\\" + strings.Repeat(" и ещё немного текста", 5),
+		Comment:  "строка 1\r\nстрока 2",
+		Repeat:   "w 1",
+		Priority: &priority,
+		Tags:     []string{"работа", "a,b"},
+		Version:  3,
+	}
+	calendar := ical.Calendar("Планировщик")
+	calendar.Components = append(calendar.Components, ical.TaskComponent(task, ical.KindTodo, time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)))
+
+	var buf bytes.Buffer
+	err := ical.Encode(&buf, calendar)
+	assert.NoError(t, err)
+
+	data := buf.String()
+	assert.True(t, strings.HasSuffix(data, "END:VCALENDAR\r\n"))
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+
+	// Развернём сложенные строки, чтобы проверить значения целиком
+	unfolded := strings.ReplaceAll(data, "\r\n ", "")
+	assert.Contains(t, unfolded, "UID:task-42@go_final_project\r\n")
+	assert.Contains(t, unfolded, "DTSTAMP:20300101T120000Z\r\n")
+	assert.Contains(t, unfolded, "SEQUENCE:2\r\n")
+	assert.Contains(t, unfolded, "DUE;VALUE=DATE:20300107\r\n")
+	assert.Contains(t, unfolded, "RRULE:FREQ=WEEKLY;BYDAY=MO\r\n")
+	assert.Contains(t, unfolded, `SUMMARY:Созвон\; план\, итоги\\ и ещё`)
+	assert.Contains(t, unfolded, `DESCRIPTION:строка 1\nстрока 2`+"\r\n")
+	assert.Contains(t, unfolded, "PRIORITY:1\r\n")
+	assert.Contains(t, unfolded, `CATEGORIES:работа,a\,b`+"\r\n")
+}
+
+func TestCalendarFeed(t *testing.T) {
+	id := addTask(t, task{
+		date:   time.Now().Format(`20060102`),
+		title:  "Задача для календаря",
+		repeat: "d 2",
+	})
+
+	ret, err := postJSON("api/feeds?feed=calendar", nil, http.MethodPost)
+	assert.NoError(t, err)
+	url, _ := ret["url"].(string)
+	assert.True(t, strings.HasPrefix(url, "/api/calendar.ics?token="), url)
+
+	// Календарь ходит без куки, только с токеном в адресе
+	resp, err := http.Get(getURL(strings.TrimPrefix(url, "/")))
+	if assert.NoError(t, err) {
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(resp.Body)
+		resp.Body.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/calendar")
+		assert.Contains(t, buf.String(), fmt.Sprintf("UID:task-%s@go_final_project\r\n", id))
+		assert.Contains(t, buf.String(), "RRULE:FREQ=DAILY;INTERVAL=2\r\n")
+	}
+
+	resp, err = http.Get(getURL("api/calendar.ics?token=wrong"))
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	// После отключения ленты прежний токен не действует
+	status, err := requestStatus("api/feeds?feed=calendar", http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	resp, err = http.Get(getURL(strings.TrimPrefix(url, "/")))
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	status, err = requestStatus("api/task?id="+id, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
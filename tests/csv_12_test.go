@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskcsv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	work := int64(1)
+	doc := models.Export{
+		Projects: []models.ExportProject{{ID: work, Name: "Работа"}},
+		Tasks: []models.ExportTask{
+			{ID: "1", Date: "20300201", Title: "Отчёт", Comment: "квартальный, \"итоговый\"", Repeat: "m 1", Priority: 2, ProjectID: &work, Tags: []string{"отчёты", "срочно"}},
+			{ID: "2", Date: "20300105", Title: "Полить цветы", Repeat: "d 3"},
+		},
+	}
+
+	for _, opts := range []taskcsv.Options{
+		{},
+		{Comma: ';', DateFormat: "DD.MM.YYYY"},
+		{Comma: '\t', Columns: map[string]string{taskcsv.FieldTitle: "Задача", taskcsv.FieldDate: "Срок"}},
+	} {
+		var buf bytes.Buffer
+		err := taskcsv.Write(&buf, doc, opts)
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("\xef\xbb\xbf")))
+
+		got, rows, rowErrors, err := taskcsv.Read(&buf, opts)
+		assert.NoError(t, err)
+		assert.Empty(t, rowErrors)
+		assert.Equal(t, []int{2, 3}, rows)
+		if !assert.Len(t, got.Tasks, 2) {
+			continue
+		}
+		for i, want := range doc.Tasks {
+			task := got.Tasks[i]
+			assert.Equal(t, want.Title, task.Title)
+			assert.Equal(t, want.Date, task.Date)
+			assert.Equal(t, want.Comment, task.Comment)
+			assert.Equal(t, want.Repeat, task.Repeat)
+			assert.Equal(t, want.Priority, task.Priority)
+			assert.Equal(t, want.Tags, task.Tags)
+		}
+		if assert.NotNil(t, got.Tasks[0].ProjectID) && assert.Len(t, got.Projects, 1) {
+			assert.Equal(t, "Работа", got.Projects[0].Name)
+			assert.Equal(t, got.Projects[0].ID, *got.Tasks[0].ProjectID)
+		}
+	}
+}
+
+func TestCSVFormulaEscape(t *testing.T) {
+	titles := []string{"=HYPERLINK(\"http://example.com\")", "+7 999 123-45-67", "-минус", "@коллега", "'в кавычках", "''=уже экранировано", "обычная"}
+	doc := models.Export{Tasks: []models.ExportTask{}}
+	for i, title := range titles {
+		doc.Tasks = append(doc.Tasks, models.ExportTask{ID: fmt.Sprint(i), Date: "20300101", Title: title, Comment: "=1+1", Tags: []string{"-тег"}})
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, taskcsv.Write(&buf, doc, taskcsv.Options{}))
+	// Ни одна ячейка не начинается с символа формулы
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(buf.Bytes(), []byte("\xef\xbb\xbf")))).ReadAll()
+	assert.NoError(t, err)
+	for _, record := range records[1:] {
+		for _, cell := range record {
+			if cell != "" {
+				assert.NotContains(t, "=+-@", cell[:1], record)
+			}
+		}
+	}
+	assert.Equal(t, []string{"'=HYPERLINK(\"http://example.com\")", "2030-01-01", "'=1+1", "", "", "", "'-тег"}, records[1])
+
+	got, _, rowErrors, err := taskcsv.Read(&buf, taskcsv.Options{})
+	assert.NoError(t, err)
+	assert.Empty(t, rowErrors)
+	if assert.Len(t, got.Tasks, len(titles)) {
+		for i, title := range titles {
+			assert.Equal(t, title, got.Tasks[i].Title)
+			assert.Equal(t, "=1+1", got.Tasks[i].Comment)
+			assert.Equal(t, []string{"-тег"}, got.Tasks[i].Tags)
+		}
+	}
+
+	// Апостроф перед обычным текстом в чужих файлах сохраняется
+	got, _, _, err = taskcsv.Read(strings.NewReader("title\n'80-е\n"), taskcsv.Options{})
+	assert.NoError(t, err)
+	if assert.Len(t, got.Tasks, 1) {
+		assert.Equal(t, "'80-е", got.Tasks[0].Title)
+	}
+}
+
+func TestCSVRead(t *testing.T) {
+	input := "Title,Date,Priority\n" +
+		"ISO,2030-01-02,\n" +
+		"Русская,03.01.2030,1\n" +
+		"\n" +
+		"Плохая дата,2030-13-01,\n" +
+		"Плохой приоритет,20300104,высокий\n" +
+		"Компактная,20300105,4\n"
+
+	doc, rows, rowErrors, err := taskcsv.Read(strings.NewReader(input), taskcsv.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 7}, rows)
+	if assert.Len(t, doc.Tasks, 3) {
+		assert.Equal(t, "20300102", doc.Tasks[0].Date)
+		assert.Equal(t, "20300103", doc.Tasks[1].Date)
+		assert.Equal(t, 1, doc.Tasks[1].Priority)
+		assert.Equal(t, "20300105", doc.Tasks[2].Date)
+	}
+	if assert.Len(t, rowErrors, 2) {
+		assert.Equal(t, 5, rowErrors[0].Row)
+		assert.Equal(t, 6, rowErrors[1].Row)
+	}
+
+	// Даты через косую черту неоднозначны и читаются только с явным форматом
+	input = "title,date\nАмериканская,01/02/2030\n"
+	_, _, rowErrors, err = taskcsv.Read(strings.NewReader(input), taskcsv.Options{})
+	assert.NoError(t, err)
+	assert.Len(t, rowErrors, 1)
+	doc, _, rowErrors, err = taskcsv.Read(strings.NewReader(input), taskcsv.Options{DateFormat: "MM/DD/YYYY"})
+	assert.NoError(t, err)
+	assert.Empty(t, rowErrors)
+	if assert.Len(t, doc.Tasks, 1) {
+		assert.Equal(t, "20300102", doc.Tasks[0].Date)
+	}
+
+	_, _, _, err = taskcsv.Read(strings.NewReader("name,date\nx,20300101\n"), taskcsv.Options{})
+	assert.ErrorIs(t, err, taskcsv.ErrNoTitleColumn)
+}
+
+func TestImportCSV(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	input := "\xef\xbb\xbfЗадача;Срок\nИз таблицы;01.02.2030\nС ошибкой;31.02.2030\n"
+	body, err := requestBody("api/import/csv?delimiter=%3B&title=%D0%97%D0%B0%D0%B4%D0%B0%D1%87%D0%B0&date=%D0%A1%D1%80%D0%BE%D0%BA&dry_run=true", input)
+	assert.NoError(t, err)
+	assert.Contains(t, body, `"row":3`)
+	assert.Contains(t, body, `"error"`)
+
+	after, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func requestBody(apipath, body string) (string, error) {
+	req, err := http.NewRequest(http.MethodPost, getURL(apipath), strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	if len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(resp.Body)
+	return buf.String(), err
+}
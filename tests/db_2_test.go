@@ -31,7 +31,8 @@ func openDB(t *testing.T) *sqlx.DB {
 	}
 	db, err := sqlx.Connect("sqlite3", dbfile)
 	assert.NoError(t, err)
-	return db
+	// Тесты читают задачи через SELECT *, а в таблице есть и столбцы сверх полей Task
+	return db.Unsafe()
 }
 
 func TestDB(t *testing.T) {
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requestJSONStatus отправляет JSON и возвращает статус ответа и разобранное тело
+func requestJSONStatus(apipath string, values map[string]any, method string, header map[string]string) (int, map[string]any, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return 0, nil, err
+	}
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	var m map[string]any
+	err = json.NewDecoder(resp.Body).Decode(&m)
+	return resp.StatusCode, m, err
+}
+
+func addDependency(t *testing.T, taskID, blockerID string) int {
+	status, _, err := requestJSONStatus("api/dependencies", map[string]any{"task_id": taskID, "blocked_by": blockerID}, http.MethodPost, nil)
+	assert.NoError(t, err)
+	return status
+}
+
+func TestDependencies(t *testing.T) {
+	blocked := addTask(t, task{date: "20970101", title: "Покрасить стены"})
+	blocker := addTask(t, task{date: "20970101", title: "Купить краску"})
+	other := addTask(t, task{date: "20970102", title: "Повесить картину"})
+	ids := []string{blocked, blocker, other}
+	defer func() {
+		for _, id := range ids {
+			requestStatus("api/task?id="+id, http.MethodDelete)
+		}
+	}()
+
+	// Зависимость видна в обеих задачах, поэтому меняет версии обеих
+	before := taskVersions(t, ids)
+	assert.Equal(t, http.StatusOK, addDependency(t, blocked, blocker))
+	after := taskVersions(t, ids)
+	assert.NotEqual(t, before[0], after[0])
+	assert.NotEqual(t, before[1], after[1])
+	assert.Equal(t, before[2], after[2])
+	assert.Equal(t, http.StatusOK, addDependency(t, other, blocked))
+	// Повторное добавление ничего не меняет
+	before = taskVersions(t, ids)
+	assert.Equal(t, http.StatusOK, addDependency(t, blocked, blocker))
+	assert.Equal(t, before, taskVersions(t, ids))
+	assert.Equal(t, http.StatusConflict, addDependency(t, blocked, blocked))
+	// Цикл через промежуточную задачу: краска ← стены ← картина ← краска
+	assert.Equal(t, http.StatusConflict, addDependency(t, blocker, other))
+	assert.Equal(t, http.StatusNotFound, addDependency(t, blocked, "999999"))
+	assert.Equal(t, http.StatusBadRequest, addDependency(t, blocked, "краска"))
+
+	assert.Equal(t, []any{blocker}, getTask(t, blocked)["blocked_by"])
+	assert.Equal(t, []any{blocked}, getTask(t, blocker)["blocks"])
+
+	const year = "from=20970101&to=20971231"
+	assert.Equal(t, []string{blocker}, taskIDs(taskList(t, year+"&ready=true")))
+	assert.Equal(t, []string{blocked, other}, taskIDs(taskList(t, year+"&ready=false")))
+
+	status, ret, err := requestJSONStatus("api/task/done?id="+blocked, nil, http.MethodPost, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, []any{blocker}, ret["blocked_by"])
+
+	before = taskVersions(t, ids)
+	status, err = requestStatus("api/dependencies?task_id="+other+"&blocked_by="+blocked, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	after = taskVersions(t, ids)
+	assert.NotEqual(t, before[0], after[0])
+	assert.Equal(t, before[1], after[1])
+	assert.NotEqual(t, before[2], after[2])
+	status, err = requestStatus("api/dependencies?task_id="+other+"&blocked_by="+blocked, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// С force=true задачу можно закрыть, не дожидаясь блокирующих
+	before = taskVersions(t, ids)
+	status, err = requestStatus("api/task/done?id="+blocked+"&force=true", http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	status, err = requestStatus("api/task?id="+blocked, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+	// Удалённая задача больше никого не блокирует
+	assert.NotContains(t, getTask(t, blocker), "blocks")
+	assert.NotEqual(t, before[1], getTask(t, blocker)["version"])
+}
+
+func TestRecurringBlocker(t *testing.T) {
+	weekly := addTask(t, task{date: "20970105", title: "Еженедельный отчёт", repeat: "d 7"})
+	review := addTask(t, task{date: "20970106", title: "Разбор отчёта"})
+	defer func() {
+		requestStatus("api/task?id="+weekly, http.MethodDelete)
+		requestStatus("api/task?id="+review, http.MethodDelete)
+	}()
+
+	assert.Equal(t, http.StatusOK, addDependency(t, review, weekly))
+	status, err := requestStatus("api/task/done?id="+review, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// Выполненная повторяющаяся задача переносится, но ожидающие её задачи освобождаются
+	version := getTask(t, review)["version"]
+	status, err = requestStatus("api/task/done?id="+weekly, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	task := getTask(t, weekly)
+	assert.Equal(t, "20970112", task["date"])
+	assert.NotContains(t, task, "blocks")
+	assert.NotContains(t, getTask(t, review), "blocked_by")
+	assert.NotEqual(t, version, getTask(t, review)["version"])
+	assert.Equal(t, []string{review}, taskIDs(taskList(t, "from=20970101&to=20970110&ready=true")))
+
+	status, err = requestStatus("api/task/done?id="+review, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
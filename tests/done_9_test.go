@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrentDone отправляет n одновременных запросов на выполнение задачи
+// и возвращает количество ответов с каждым статусом
+func concurrentDone(t *testing.T, apipath string, n int) map[int]int {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		statuses = make(map[int]int)
+	)
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			code, err := requestStatus(apipath, http.MethodPost)
+			assert.NoError(t, err)
+			mu.Lock()
+			statuses[code]++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return statuses
+}
+
+func TestConcurrentDone(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	const clicks = 10
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	id := addTask(t, task{
+		date:   today,
+		title:  "Полить цветы",
+		repeat: "d 2",
+	})
+
+	statuses := concurrentDone(t, "api/task/done?id="+id+"&date="+today, clicks)
+	assert.Equal(t, 1, statuses[http.StatusOK])
+	assert.Equal(t, clicks-1, statuses[http.StatusConflict])
+
+	var stored Task
+	err := db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), stored.Date)
+
+	// Разовая задача удаляется ровно один раз, остальные запросы получают 409
+	id = addTask(t, task{
+		date:  today,
+		title: "Оплатить счёт",
+	})
+
+	statuses = concurrentDone(t, "api/task/done?id="+id+"&date="+today, clicks)
+	assert.Equal(t, 1, statuses[http.StatusOK])
+	assert.Equal(t, clicks, statuses[http.StatusOK]+statuses[http.StatusConflict]+statuses[http.StatusNotFound])
+	notFoundTask(t, id)
+}
+
+func TestTransactionLocks(t *testing.T) {
+	localDB(t)
+
+	// Читающие транзакции не берут блокировку записи и не мешают друг другу
+	var readers []*sql.Tx
+	for i := 0; i < 2; i++ {
+		tx, err := db.GetDB().Begin()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer tx.Rollback()
+		var n int
+		assert.NoError(t, tx.QueryRow("SELECT count(*) FROM scheduler").Scan(&n))
+		readers = append(readers, tx)
+	}
+	for _, tx := range readers {
+		assert.NoError(t, tx.Rollback())
+	}
+
+	// Пишущая транзакция ждёт, пока другая закончит, а не падает
+	first, err := db.BeginWrite()
+	if !assert.NoError(t, err) {
+		return
+	}
+	started := make(chan error)
+	go func() {
+		second, err := db.BeginWrite()
+		if err == nil {
+			err = second.Rollback()
+		}
+		started <- err
+	}()
+	select {
+	case <-started:
+		t.Error("вторая пишущая транзакция началась, пока первая держит блокировку")
+	case <-time.After(200 * time.Millisecond):
+	}
+	assert.NoError(t, first.Rollback())
+	assert.NoError(t, <-started)
+}
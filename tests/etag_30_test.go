@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/handlers"
+	"github.com/stretchr/testify/assert"
+)
+
+// taskRequest отправляет запрос к задаче с заголовком If-Match, если он не пустой,
+// и возвращает статус и ETag ответа
+func taskRequest(t *testing.T, baseURL, method, query string, values map[string]any, ifMatch string) (int, string) {
+	var body bytes.Buffer
+	if values != nil {
+		assert.NoError(t, json.NewEncoder(&body).Encode(values))
+	}
+	req, err := http.NewRequest(method, baseURL+"api/task"+query, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	if len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("ETag")
+}
+
+func TestETag(t *testing.T) {
+	base := getURL("")
+	id := addTask(t, task{date: "20980101", title: "Версионная задача"})
+	defer requestStatus("api/task?id="+id, http.MethodDelete)
+
+	status, etag := taskRequest(t, base, http.MethodGet, "?id="+id, nil, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, fmt.Sprintf(`"%v"`, getTask(t, id)["version"]), etag)
+
+	update := map[string]any{"id": id, "date": "20980102", "title": "Первая правка"}
+	status, changed := taskRequest(t, base, http.MethodPut, "", update, etag)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEqual(t, etag, changed)
+
+	// Правка поверх чужих изменений отклоняется, задача остаётся как есть
+	update["title"] = "Устаревшая правка"
+	status, _ = taskRequest(t, base, http.MethodPut, "", update, etag)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	status, _ = taskRequest(t, base, http.MethodDelete, "?id="+id, nil, etag)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	assert.Equal(t, "Первая правка", getTask(t, id)["title"])
+
+	// Версия из тела запроса проверяется так же, как If-Match
+	update["version"] = getTask(t, id)["version"]
+	update["title"] = "Вторая правка"
+	status, _ = taskRequest(t, base, http.MethodPut, "", update, "")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = taskRequest(t, base, http.MethodPut, "", update, "")
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	delete(update, "version")
+
+	for _, header := range []string{`"abc"`, `"0"`, "-1"} {
+		status, _ = taskRequest(t, base, http.MethodPut, "", update, header)
+		assert.Equal(t, http.StatusPreconditionFailed, status, header)
+	}
+	status, _ = taskRequest(t, base, http.MethodPut, "", update, "*")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = taskRequest(t, base, http.MethodPut, "?id=999999", map[string]any{"id": "999999", "date": "20980102", "title": "Нет"}, `"1"`)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// Слабый ETag сравнивается как сильный
+	_, etag = taskRequest(t, base, http.MethodGet, "?id="+id, nil, "")
+	status, _ = taskRequest(t, base, http.MethodDelete, "?id="+id, nil, "W/"+etag)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	localDB(t)
+	keepConfig(t)
+	config.AppConfig.RequireIfMatch = true
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/task", handlers.HandleTask)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	base := srv.URL + "/"
+
+	// Создание и чтение не требуют If-Match
+	var created map[string]any
+	resp, err := http.Post(base+"api/task", "application/json", bytes.NewReader([]byte(`{"date": "20980101", "title": "Строгая задача"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	id := fmt.Sprint(created["id"])
+	status, etag := taskRequest(t, base, http.MethodGet, "?id="+id, nil, "")
+	assert.Equal(t, http.StatusOK, status)
+
+	update := map[string]any{"id": id, "date": "20980102", "title": "Правка"}
+	status, _ = taskRequest(t, base, http.MethodPut, "", update, "")
+	assert.Equal(t, http.StatusPreconditionRequired, status)
+	status, _ = taskRequest(t, base, http.MethodDelete, "?id="+id, nil, "")
+	assert.Equal(t, http.StatusPreconditionRequired, status)
+
+	status, etag = taskRequest(t, base, http.MethodPut, "", update, etag)
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = taskRequest(t, base, http.MethodDelete, "?id="+id, nil, etag)
+	assert.Equal(t, http.StatusOK, status)
+}
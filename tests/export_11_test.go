@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func importJSON(t *testing.T, query string, doc map[string]any) map[string]any {
+	ret, err := postJSON("api/import"+query, doc, http.MethodPost)
+	assert.NoError(t, err)
+	return ret
+}
+
+func TestExportImport(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().AddDate(0, 0, 3).Format(`20060102`)
+	id := addTask(t, task{
+		date:    date,
+		title:   "Экспортируемая задача",
+		comment: "с комментарием",
+		repeat:  "w 1,5",
+	})
+
+	body, err := requestJSON("api/export", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var doc map[string]any
+	err = json.Unmarshal(body, &doc)
+	assert.NoError(t, err)
+	assert.Equal(t, "go_final_project/tasks", doc["format"])
+
+	tasks, _ := doc["tasks"].([]any)
+	var exported map[string]any
+	for _, v := range tasks {
+		if m, ok := v.(map[string]any); ok && m["id"] == id {
+			exported = m
+		}
+	}
+	if !assert.NotNil(t, exported) {
+		return
+	}
+	assert.Equal(t, "w 1,5", exported["repeat"])
+
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	// Повторный импорт того же документа ничего не добавляет
+	ret := importJSON(t, "", doc)
+	assert.Nil(t, ret["error"])
+	assert.Equal(t, 0.0, ret["created"])
+	assert.Equal(t, float64(len(tasks)), ret["skipped"])
+
+	// Предпросмотр новой задачи не меняет базу
+	fresh := map[string]any{"id": "new", "date": date, "title": "Новая из импорта", "blocked_by": []string{id}}
+	doc["tasks"] = append(tasks, fresh)
+	ret = importJSON(t, "?dry_run=true", doc)
+	assert.Nil(t, ret["error"])
+	assert.Equal(t, 1.0, ret["created"])
+	assert.Equal(t, true, ret["dry_run"])
+
+	after, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	// Ошибочная задача отклоняет весь документ
+	fresh["repeat"] = "ooops"
+	ret = importJSON(t, "", doc)
+	assert.NotNil(t, ret["error"])
+	assert.NotEmpty(t, ret["errors"])
+
+	after, err = count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/taskdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchIndex(t *testing.T) {
+	localDB(t)
+
+	// FTS5 есть только в сборке с тегом sqlite_fts5, без него поиск идёт через LIKE
+	var compiled bool
+	err := db.GetDB().QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&compiled)
+	assert.NoError(t, err)
+	assert.Equal(t, compiled, db.FTSEnabled())
+
+	search := func(text string) []models.Task {
+		page, err := taskdb.NewTaskQuery().Search(text).List(taskdb.Page{})
+		assert.NoError(t, err)
+		return page.Tasks
+	}
+	ids := func(tasks []models.Task) []string {
+		ids := []string{}
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+
+	id, err := taskdb.AddTaskToDB(models.Task{Date: "20300101", Title: "Индексируемый черновик", Comment: "про фазана"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	taskID := strconv.FormatInt(id, 10)
+	assert.Equal(t, []string{taskID}, ids(search("черновик")))
+	assert.Equal(t, []string{taskID}, ids(search("фазан")))
+
+	// Изменение заголовка попадает в индекс: старое слово больше не находится
+	_, err = taskdb.UpdateTaskInDB(models.Task{ID: taskID, Date: "20300101", Title: "Индексируемый чистовик <b>", Comment: "про фазана"})
+	assert.NoError(t, err)
+	assert.Empty(t, search("черновик"))
+	assert.Equal(t, []string{taskID}, ids(search("чистовик")))
+
+	// Регистр не важен и для кириллицы, в том числе без FTS5
+	assert.Equal(t, []string{taskID}, ids(search("ЧИСТОВ")))
+	assert.Equal(t, []string{taskID}, ids(search("индексируемый")))
+
+	if db.FTSEnabled() {
+		// Поиск по началу слова с подсвеченным фрагментом, текст задачи в нём экранирован
+		tasks := search("ЧИСТОВ")
+		if assert.Len(t, tasks, 1) {
+			assert.Contains(t, tasks[0].Snippet, "<mark>чистовик</mark> &lt;b&gt;")
+		}
+		assert.Empty(t, search("стовик"))
+	} else {
+		// LIKE находит и середину слова, но фрагмента не даёт
+		tasks := search("стовик")
+		if assert.Len(t, tasks, 1) {
+			assert.Empty(t, tasks[0].Snippet)
+		}
+	}
+
+	assert.NoError(t, taskdb.DeleteTaskFromDB(int(id), 0))
+	assert.Empty(t, search("чистовик"))
+	assert.Empty(t, search("фазан"))
+
+	if db.FTSEnabled() {
+		var indexed int
+		err = db.GetDB().QueryRow("SELECT count(*) FROM scheduler_fts WHERE scheduler_fts MATCH ?", `"фазана"`).Scan(&indexed)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, indexed)
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/deeramster/go_final_project/config"
+	"github.com/deeramster/go_final_project/db"
+	"github.com/stretchr/testify/assert"
+)
+
+var localDBOnce sync.Once
+
+// localDB открывает в процессе теста отдельную базу, чтобы проверять пакеты напрямую, не трогая задачи сервера
+func localDB(t *testing.T) {
+	localDBOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "scheduler-tests")
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.AppConfig.DBFile = filepath.Join(dir, "scheduler.db")
+		config.AppConfig.MaxImportSize = 1 << 20
+		db.InitDB()
+	})
+}
+
+// taskList запрашивает список задач с параметрами query и возвращает разобранный ответ
+func taskList(t *testing.T, query string) map[string]any {
+	body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m), string(body))
+	return m
+}
+
+// taskIDs возвращает идентификаторы задач из ответа списка по порядку
+func taskIDs(m map[string]any) []string {
+	ids := []string{}
+	tasks, _ := m["tasks"].([]any)
+	for _, v := range tasks {
+		task, _ := v.(map[string]any)
+		ids = append(ids, fmt.Sprint(task["id"]))
+	}
+	return ids
+}
+
+// deleteTasks удаляет задачи, созданные тестом
+func deleteTasks(t *testing.T, ids []string) {
+	for _, id := range ids {
+		ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"], id)
+	}
+}
+
+// requestStatus выполняет запрос без тела и возвращает только код ответа
+func requestStatus(apipath string, method string) (int, error) {
+	req, err := http.NewRequest(method, getURL(apipath), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	client := &http.Client{}
+	if len(Token) > 0 {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return 0, err
+		}
+		jar.SetCookies(req.URL, []*http.Cookie{
+			{
+				Name:  "token",
+				Value: Token,
+			},
+		})
+		client.Jar = jar
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// getTask возвращает задачу по идентификатору
+func getTask(t *testing.T, id string) map[string]any {
+	task, err := postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	return task
+}
+
+// taskVersions возвращает версии задач по порядку
+func taskVersions(t *testing.T, ids []string) []any {
+	versions := make([]any, len(ids))
+	for i, id := range ids {
+		versions[i] = getTask(t, id)["version"]
+	}
+	return versions
+}
+
+// keepConfig возвращает настройки приложения к прежним значениям по окончании теста
+func keepConfig(t *testing.T) {
+	old := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = old })
+}
+
+// doRequest выполняет запрос и возвращает ответ вместе с прочитанным телом
+func doRequest(t *testing.T, client *http.Client, req *http.Request) (*http.Response, string) {
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp, string(body)
+}
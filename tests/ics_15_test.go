@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deeramster/go_final_project/ical"
+	"github.com/deeramster/go_final_project/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatFromRRule(t *testing.T) {
+	// 7 января 2030 года — понедельник
+	date := time.Date(2030, 1, 7, 0, 0, 0, 0, time.Local)
+	tbl := []struct {
+		rrule  string
+		repeat string
+		lost   int
+	}{
+		{"FREQ=DAILY", "d 1", 0},
+		{"FREQ=DAILY;INTERVAL=3;COUNT=10", "d 3", 1},
+		{"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", "w 1,2,3,4,5", 0},
+		{"FREQ=WEEKLY", "w 1", 0},
+		{"FREQ=WEEKLY;BYDAY=FR,MO;WKST=MO", "w 1,5", 0},
+		{"FREQ=WEEKLY;INTERVAL=2", "d 14", 0},
+		{"FREQ=MONTHLY", "m 7", 0},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-1", "m 1,-1", 0},
+		{"FREQ=MONTHLY;INTERVAL=3", "m 7 1,4,7,10", 0},
+		{"FREQ=YEARLY;UNTIL=20401231T000000Z", "y", 1},
+		{"FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=8", "m 8 3", 0},
+		{"FREQ=MONTHLY;BYDAY=2MO", "", 1},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "", 1},
+		{"FREQ=HOURLY", "", 1},
+		{"FREQ=MONTHLY;BYSETPOS=-1;BYDAY=FR", "", 1},
+	}
+	for _, v := range tbl {
+		repeat, lost := ical.RepeatFromRRule(v.rrule, date)
+		assert.Equal(t, v.repeat, repeat, v.rrule)
+		assert.Len(t, lost, v.lost, v.rrule)
+	}
+}
+
+func TestICSRoundTrip(t *testing.T) {
+	priority := 2
+	tasks := []models.Task{
+		{ID: "1", Date: "20300107", Title: "Планёрка; команда, все", Comment: "повестка\nвторой пункт", Repeat: "w 1,3", Priority: &priority, Tags: []string{"работа"}},
+		{ID: "2", Date: "20300131", Title: "Отчёт", Repeat: "m -1 1,6"},
+		{ID: "3", Date: "20300105", Title: "Полить цветы", Repeat: "d 3"},
+		{ID: "4", Date: "20300101", Title: "Новый год", Repeat: "y"},
+		{ID: "5", Date: "20300110", Title: "Разовая"},
+	}
+
+	for _, kind := range []string{ical.KindEvent, ical.KindTodo} {
+		calendar := ical.Calendar("Планировщик")
+		for _, task := range tasks {
+			calendar.Components = append(calendar.Components, ical.TaskComponent(task, kind, time.Now()))
+		}
+		var buf bytes.Buffer
+		assert.NoError(t, ical.Encode(&buf, calendar))
+
+		doc, lines, errs, warnings, err := ical.ReadTasks(&buf)
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+		assert.Empty(t, warnings)
+		assert.Len(t, lines, len(tasks))
+		if !assert.Len(t, doc.Tasks, len(tasks)) {
+			continue
+		}
+		for i, want := range tasks {
+			got := doc.Tasks[i]
+			assert.Equal(t, want.Title, got.Title)
+			assert.Equal(t, want.Comment, got.Comment)
+			assert.Equal(t, want.Date, got.Date)
+			assert.Equal(t, want.Repeat, got.Repeat)
+		}
+		assert.Equal(t, 2, doc.Tasks[0].Priority)
+		assert.Equal(t, []string{"работа"}, doc.Tasks[0].Tags)
+	}
+}
+
+func TestICSDecode(t *testing.T) {
+	input := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\n" +
+		"DTSTART;VALUE=DATE:20300301\n" +
+		"DTEND;VALUE=DATE:20300304\n" +
+		"SUMMARY:Длинное назв\n" +
+		" ание\n" +
+		"X-PARAM;X-NOTE=\"a:b;c\":значение\n" +
+		"END:VEVENT\n" +
+		"BEGIN:VTODO\n" +
+		"SUMMARY:Сделано\n" +
+		"STATUS:COMPLETED\n" +
+		"END:VTODO\n" +
+		"BEGIN:VEVENT\n" +
+		"DTSTART;VALUE=DATE:20300305\n" +
+		"SUMMARY:Отменено\n" +
+		"STATUS:CANCELLED\n" +
+		"END:VEVENT\n" +
+		"BEGIN:VEVENT\n" +
+		"DTSTART;VALUE=DATE:20300310\n" +
+		"RECURRENCE-ID;VALUE=DATE:20300309\n" +
+		"SUMMARY:Перенесённый повтор\n" +
+		"END:VEVENT\n" +
+		"BEGIN:VTODO\n" +
+		"DUE:2030-03-11\n" +
+		"SUMMARY:Плохая дата\n" +
+		"END:VTODO\n" +
+		"END:VCALENDAR\n"
+
+	doc, lines, errs, warnings, err := ical.ReadTasks(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, lines)
+	if assert.Len(t, doc.Tasks, 1) {
+		assert.Equal(t, "Длинное название", doc.Tasks[0].Title)
+		assert.Equal(t, "20300301", doc.Tasks[0].Date)
+	}
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, 23, errs[0].Line)
+	}
+	// Событие на несколько дней теряет дату окончания, выполненная, отменённая
+	// и изменённый повтор пропускаются с предупреждением
+	if assert.Len(t, warnings, 4) {
+		assert.Contains(t, warnings[0].Message, "20300303")
+		for i, line := range []int{9, 13, 18} {
+			assert.Equal(t, line, warnings[i+1].Line)
+			assert.Contains(t, warnings[i+1].Message, "skipped")
+		}
+	}
+
+	_, _, _, _, err = ical.ReadTasks(strings.NewReader("BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VCALENDAR\n"))
+	assert.Error(t, err)
+	_, _, _, _, err = ical.ReadTasks(strings.NewReader("просто текст"))
+	assert.Error(t, err)
+}
+
+func TestImportICS(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\nUID:a\r\nDTSTART:20300107T090000Z\r\nSUMMARY:Из календаря\r\nRRULE:FREQ=MONTHLY;BYDAY=1MO\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	body, err := requestBody("api/import/ics?dry_run=true", input)
+	assert.NoError(t, err)
+	assert.Contains(t, body, `"created":1`)
+	assert.Contains(t, body, `"preview"`)
+	assert.Contains(t, body, `Из календаря`)
+	assert.Contains(t, body, `"warnings"`)
+	assert.Contains(t, body, `can't be represented`)
+
+	// Выполненная задача не мешает импорту остальных
+	input = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VTODO\r\nUID:b\r\nSUMMARY:Сделано\r\nSTATUS:COMPLETED\r\nEND:VTODO\r\n" +
+		"BEGIN:VTODO\r\nUID:c\r\nDUE;VALUE=DATE:20300108\r\nSUMMARY:Осталось\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+	body, err = requestBody("api/import/ics?dry_run=true", input)
+	assert.NoError(t, err)
+	assert.Contains(t, body, `"created":1`)
+	assert.Contains(t, body, "completed task skipped")
+	assert.NotContains(t, body, `"errors"`)
+
+	after, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+}
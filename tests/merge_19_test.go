@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/deeramster/go_final_project/db"
+	"github.com/deeramster/go_final_project/taskdb"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportMatch(t *testing.T) {
+	date := time.Now().AddDate(0, 0, 4).Format(`20060102`)
+	id := addTask(t, task{
+		date:    date,
+		title:   "Задача для сверки",
+		comment: "как было",
+	})
+
+	doc := map[string]any{
+		"format":  "go_final_project/tasks",
+		"version": 1,
+		"tasks": []map[string]any{
+			{"id": "1", "date": date, "title": "Задача для сверки", "comment": "как было"},
+			{"id": "2", "date": date, "title": "Задача для сверки", "comment": "по-другому"},
+		},
+	}
+	ret := importJSON(t, "?dry_run=true", doc)
+	assert.Equal(t, 0.0, ret["created"])
+	assert.Equal(t, 2.0, ret["skipped"])
+	assert.Len(t, ret["duplicates"], 1)
+	if conflicts, ok := ret["conflicts"].([]any); assert.True(t, ok) && assert.Len(t, conflicts, 1) {
+		conflict, _ := conflicts[0].(map[string]any)
+		assert.Equal(t, "matches task "+id+" but differs in comment", conflict["message"])
+	}
+
+	// По названию и дате задача на другой день — уже не дубликат
+	doc["tasks"] = []map[string]any{{"id": "1", "date": time.Now().AddDate(0, 0, 5).Format(`20060102`), "title": "Задача для сверки"}}
+	ret = importJSON(t, "?dry_run=true&match=title,date", doc)
+	assert.Equal(t, 1.0, ret["created"])
+	ret = importJSON(t, "?dry_run=true&match=title", doc)
+	assert.Equal(t, 0.0, ret["created"])
+	assert.Len(t, ret["conflicts"], 1)
+
+	ret = importJSON(t, "?dry_run=true&match=comment", doc)
+	assert.NotNil(t, ret["error"])
+
+	status, err := requestStatus("api/task?id="+id, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestOpenCopy(t *testing.T) {
+	// База первой версии: только таблица задач без проектов, меток и версий
+	path := filepath.Join(t.TempDir(), "old.db")
+	old, err := sqlx.Connect("sqlite3", path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = old.Exec(`CREATE TABLE scheduler (id INTEGER PRIMARY KEY AUTOINCREMENT, date TEXT, title TEXT NOT NULL, comment TEXT, repeat TEXT);
+  INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20300101', 'Старая задача', 'из первой версии', 'd 7');`)
+	assert.NoError(t, err)
+	old.Close()
+	// Символы, значимые в URI, остаются частью имени файла
+	renamed := filepath.Join(filepath.Dir(path), "old 100%?#.db")
+	assert.NoError(t, os.Rename(path, renamed))
+	path = renamed
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+
+	localDB(t)
+	fts := db.FTSEnabled()
+	conn, cleanup, err := db.OpenCopy(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	doc, err := taskdb.ExportFrom(conn)
+	assert.NoError(t, err)
+	if assert.Len(t, doc.Tasks, 1) {
+		assert.Equal(t, "Старая задача", doc.Tasks[0].Title)
+		assert.Equal(t, "d 7", doc.Tasks[0].Repeat)
+	}
+	// Подготовка копии не меняет состояние рабочей базы
+	assert.Equal(t, fts, db.FTSEnabled())
+
+	// Вложения не переносятся, их число сообщается по задачам
+	_, err = conn.Exec(`INSERT INTO attachments (task_id, name, content_type, size, stored_name) VALUES
+  (1, 'a.txt', 'text/plain', 1, 'a'), (1, 'b.txt', 'text/plain', 1, 'b')`)
+	assert.NoError(t, err)
+	counts, err := taskdb.AttachmentCountsFrom(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{doc.Tasks[0].ID: 2}, counts)
+	cleanup()
+
+	// Сам файл не изменился
+	after, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, info.Size(), after.Size())
+	assert.Equal(t, info.ModTime(), after.ModTime())
+
+	_, _, err = db.OpenCopy(filepath.Join(t.TempDir(), "missing.db"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateMonthRule(t *testing.T) {
+	tbl := []struct {
+		now, date, repeat, want string
+	}{
+		// Последний день месяца, в том числе в феврале високосного и обычного года
+		{"20240126", "20240115", "m -1", "20240131"},
+		{"20240131", "20240131", "m -1", "20240229"},
+		{"20240229", "20240229", "m -1", "20240331"},
+		{"20240331", "20240331", "m -1", "20240430"},
+		{"20230131", "20230131", "m -1", "20230228"},
+		{"20240126", "20200115", "m -1", "20240131"},
+		// Предпоследний день месяца
+		{"20240101", "20240101", "m -2", "20240130"},
+		{"20240130", "20240130", "m -2", "20240228"},
+		{"20230210", "20230210", "m -2", "20230227"},
+		{"20240228", "20240228", "m -2", "20240330"},
+		// Месяцы без 31-го числа пропускаются
+		{"20240131", "20240131", "m 31", "20240331"},
+		{"20240401", "20240401", "m 31", "20240531"},
+		{"20240731", "20240731", "m 31", "20240831"},
+		{"20240831", "20240831", "m 31", "20241031"},
+		// 29 февраля бывает только в високосные годы
+		{"20230101", "20230101", "m 29 2", "20240229"},
+		{"20240229", "20240229", "m 29 2", "20280229"},
+		{"20240301", "20240301", "m 29 2", "20280229"},
+		// Списки месяцев
+		{"20240101", "20240101", "m 1,15 3,9", "20240301"},
+		{"20240301", "20240301", "m 1,15 3,9", "20240315"},
+		{"20240315", "20240315", "m 1,15 3,9", "20240901"},
+		{"20240915", "20240915", "m 1,15 3,9", "20250301"},
+		{"20231201", "20231201", "m -1 2,12", "20231231"},
+		{"20231231", "20231231", "m -1 2,12", "20240229"},
+		{"20240229", "20240229", "m -1 2,12", "20241231"},
+		// Несуществующие даты и неверные правила
+		{"20240101", "20240101", "m 31 2,4,6", ""},
+		{"20240101", "20240101", "m 30 2", ""},
+		{"20240101", "20240101", "m 32", ""},
+		{"20240101", "20240101", "m -3", ""},
+		{"20240101", "20240101", "m 0", ""},
+		{"20240101", "20240101", "m 1 13", ""},
+		{"20240101", "20240101", "m 1 2 3", ""},
+	}
+	for _, v := range tbl {
+		now, err := time.Parse(dateutil.DateLayout, v.now)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		next, err := dateutil.NextDate(now, v.date, v.repeat)
+		if v.want == "" {
+			assert.Error(t, err, "%s %q", v.date, v.repeat)
+			continue
+		}
+		assert.NoError(t, err, "%s %q", v.date, v.repeat)
+		assert.Equal(t, v.want, next, "now %s, %s %q", v.now, v.date, v.repeat)
+	}
+}
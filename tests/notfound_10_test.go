@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotFound(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:  now.Format(`20060102`),
+		title: "Удалить и проверить",
+	})
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	// Все операции над удалённой задачей отвечают 404
+	code, err := requestStatus("api/task?id="+id, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, err = requestStatus("api/task?id="+id, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, err = requestStatus("api/task/done?id="+id, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  now.Format(`20060102`),
+		"title": "Уже нет",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Contains(t, ret, "error")
+	assert.Empty(t, ret["title"])
+	notFoundTask(t, id)
+
+	var count int
+	err = db.Get(&count, `SELECT count(*) FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestDoneVanished(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	id := addTask(t, task{
+		date:   today,
+		title:  "Исчезающая задача",
+		repeat: "d 1",
+	})
+
+	// Удаление во время выполнения: done либо успевает, либо видит 404
+	var (
+		wg   sync.WaitGroup
+		done int
+		del  int
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var err error
+		done, err = requestStatus("api/task/done?id="+id+"&date="+today, http.MethodPost)
+		assert.NoError(t, err)
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		del, err = requestStatus("api/task?id="+id, http.MethodDelete)
+		assert.NoError(t, err)
+	}()
+	wg.Wait()
+
+	assert.Contains(t, []int{http.StatusOK, http.StatusNotFound}, done)
+	assert.Equal(t, http.StatusOK, del)
+	notFoundTask(t, id)
+}
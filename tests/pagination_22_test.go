@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagination(t *testing.T) {
+	// Отдельный год, чтобы не пересекаться с задачами других тестов
+	var ids []string
+	for i, date := range []string{"20910301", "20910302", "20910302", "20910303", "20910305"} {
+		ids = append(ids, addTask(t, task{date: date, title: fmt.Sprintf("Страница %d", i+1)}))
+	}
+	defer func() { deleteTasks(t, ids) }()
+
+	const filter = "from=20910101&to=20911231"
+
+	// Без limit и cursor ответ прежний: только список задач
+	m := taskList(t, filter)
+	assert.Equal(t, ids, taskIDs(m))
+	assert.NotContains(t, m, "next_cursor")
+	assert.NotContains(t, m, "total")
+
+	var got []string
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		m = taskList(t, filter+"&limit=2&cursor="+cursor)
+		assert.Equal(t, 5.0, m["total"])
+		got = append(got, taskIDs(m)...)
+		cursor, _ = m["next_cursor"].(string)
+		if cursor == "" {
+			break
+		}
+		assert.Len(t, taskIDs(m), 2)
+	}
+	assert.Equal(t, ids, got)
+
+	// Курсор указывает на последнюю задачу страницы, поэтому удаление уже
+	// показанной задачи не сдвигает следующую страницу
+	m = taskList(t, filter+"&limit=2")
+	cursor, _ = m["next_cursor"].(string)
+	deleteTasks(t, ids[:1])
+	ids = ids[1:]
+	m = taskList(t, filter+"&limit=2&cursor="+cursor)
+	assert.Equal(t, ids[1:3], taskIDs(m))
+	assert.Equal(t, 4.0, m["total"])
+
+	// По убыванию курсор ведёт в обратную сторону
+	m = taskList(t, filter+"&sort=date&order=desc&limit=3")
+	assert.Equal(t, []string{ids[3], ids[2], ids[1]}, taskIDs(m))
+	cursor, _ = m["next_cursor"].(string)
+	m = taskList(t, filter+"&sort=date&order=desc&limit=3&cursor="+cursor)
+	assert.Equal(t, []string{ids[0]}, taskIDs(m))
+	assert.Empty(t, m["next_cursor"])
+
+	// Курсор — base64 от JSON, ключ в нём только строка или число
+	badKey := base64.RawURLEncoding.EncodeToString([]byte(`{"k":{"a":1},"id":1}`))
+	for _, query := range []string{"limit=0", "limit=501", "limit=два", "cursor=не-курсор", "cursor=e30", "cursor=" + badKey} {
+		m = taskList(t, query)
+		assert.NotNil(t, m["error"], query)
+	}
+}
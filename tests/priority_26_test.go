@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addPriorityTask(t *testing.T, date, title string, priority any) string {
+	ret, err := postJSON("api/task", map[string]any{"date": date, "title": title, "priority": priority}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	return fmt.Sprint(ret["id"])
+}
+
+func TestPriority(t *testing.T) {
+	for _, priority := range []any{5, -1, "высокий"} {
+		ret, err := postJSON("api/task", map[string]any{"date": "20950101", "title": "Неверный приоритет", "priority": priority}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["error"], priority)
+	}
+
+	ids := []string{
+		addPriorityTask(t, "20950102", "Без приоритета", nil),
+		addPriorityTask(t, "20950102", "Срочно", 1),
+		addPriorityTask(t, "20950101", "Не срочно", 4),
+		addPriorityTask(t, "20950103", "Тоже срочно", 1),
+		addPriorityTask(t, "20950102", "Средне", 2),
+	}
+	defer func() { deleteTasks(t, ids) }()
+
+	task := getTask(t, ids[1])
+	assert.Equal(t, 1.0, task["priority"])
+	assert.NotContains(t, getTask(t, ids[0]), "priority")
+
+	// Внутри дня сначала срочные задачи, задачи без приоритета — последними
+	const year = "from=20950101&to=20951231"
+	assert.Equal(t, []string{ids[2], ids[1], ids[4], ids[0], ids[3]}, taskIDs(taskList(t, year)))
+	// По убыванию даты срочные задачи дня всё равно первые
+	assert.Equal(t, []string{ids[3], ids[1], ids[4], ids[0], ids[2]}, taskIDs(taskList(t, year+"&order=desc&sort=date")))
+	assert.Equal(t, []string{ids[1], ids[3], ids[4], ids[2], ids[0]}, taskIDs(taskList(t, year+"&sort=priority")))
+	assert.Equal(t, []string{ids[1], ids[4], ids[3]}, taskIDs(taskList(t, year+"&priority=1,2")))
+	assert.Equal(t, []string{ids[0]}, taskIDs(taskList(t, year+"&priority=0")))
+
+	// Без priority в запросе изменения приоритет сохраняется, 0 — сбрасывает
+	update := map[string]any{"id": ids[1], "date": "20950102", "title": "Уже не срочно"}
+	ret, err := postJSON("api/task", update, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	assert.Equal(t, 1.0, getTask(t, ids[1])["priority"])
+
+	update["priority"] = 0
+	ret, err = postJSON("api/task", update, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	assert.NotContains(t, getTask(t, ids[1]), "priority")
+
+	update["priority"] = 9
+	ret, err = postJSON("api/task", update, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+}
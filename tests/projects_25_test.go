@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addProject(t *testing.T, name, color string) string {
+	ret, err := postJSON("api/projects", map[string]any{"name": name, "color": color}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	id := fmt.Sprint(ret["id"])
+	assert.NotEmpty(t, id)
+	return id
+}
+
+func TestProjects(t *testing.T) {
+	for _, project := range []map[string]any{{"name": "  "}, {"name": "Цвет", "color": "красный"}} {
+		ret, err := postJSON("api/projects", project, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["error"], project)
+	}
+
+	work := addProject(t, "Работа", "#3366ff")
+	home := addProject(t, "Дом", "")
+
+	ret, err := postJSON("api/task", map[string]any{"date": "20940101", "title": "В проекте", "project_id": work}, http.MethodPost)
+	assert.NoError(t, err)
+	// project_id — число, а не строка
+	assert.NotNil(t, ret["error"])
+
+	var workID, homeID int64
+	fmt.Sscan(work, &workID)
+	fmt.Sscan(home, &homeID)
+	var ids []string
+	for _, projectID := range []any{workID, workID, nil} {
+		ret, err := postJSON("api/task", map[string]any{"date": "20940101", "title": "Задача проекта", "project_id": projectID}, http.MethodPost)
+		assert.NoError(t, err)
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+	defer func() { deleteTasks(t, ids[1:]) }()
+
+	ret, err = postJSON("api/task", map[string]any{"date": "20940101", "title": "Чужой проект", "project_id": 999999}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+
+	project, err := postJSON("api/projects?id="+work, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, "Работа", project["name"])
+	assert.Equal(t, "#3366ff", project["color"])
+	assert.Equal(t, 2.0, project["task_count"])
+
+	const year = "from=20940101&to=20941231"
+	assert.Equal(t, ids[:2], taskIDs(taskList(t, year+"&project="+work)))
+	assert.Equal(t, ids[2:], taskIDs(taskList(t, year+"&project=inbox")))
+
+	ret, err = postJSON("api/projects/move", map[string]any{"task_ids": ids[1:], "project_id": homeID}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, ret["moved"])
+	assert.Equal(t, ids[1:], taskIDs(taskList(t, year+"&project="+home)))
+
+	ret, err = postJSON("api/projects/move", map[string]any{"task_ids": ids[2:], "project_id": nil}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, ret["moved"])
+	assert.Equal(t, ids[2:], taskIDs(taskList(t, year+"&project=inbox")))
+
+	ret, err = postJSON("api/projects/move", map[string]any{"task_ids": ids[:1], "project_id": 999999}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+
+	ret, err = postJSON("api/projects", map[string]any{"id": workID, "name": "Работа и учёба", "color": "#000000"}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Equal(t, "Работа и учёба", ret["name"])
+	ret, err = postJSON("api/projects", map[string]any{"id": 999999, "name": "Нет такого"}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+
+	// Без cascade задачи проекта переходят во «Входящие»
+	status, err := requestStatus("api/projects?id="+home, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, ids[1:], taskIDs(taskList(t, year+"&project=inbox")))
+
+	// С cascade удаляются и задачи проекта
+	status, err = requestStatus("api/projects?id="+work+"&cascade=true", http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	status, err = requestStatus("api/task?id="+ids[0], http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	for _, path := range []string{"api/projects?id=" + work, "api/projects?id=abc"} {
+		status, err = requestStatus(path, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.NotEqual(t, http.StatusOK, status, path)
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskQuery(t *testing.T) {
+	ids := []string{
+		addTask(t, task{date: "20920110", title: "Бета", comment: "с комментарием", repeat: "d 5"}),
+		addTask(t, task{date: "20920105", title: "Альфа", repeat: "w 1,3"}),
+		addTask(t, task{date: "20920120", title: "Гамма", comment: "тоже"}),
+		addTask(t, task{date: "20920301", title: "Дельта", repeat: "y"}),
+	}
+	defer func() { deleteTasks(t, ids) }()
+
+	// Просроченную задачу через API не создать: дата в прошлом сдвигается на сегодня
+	db := openDB(t)
+	defer db.Close()
+	res, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES ('19920101', 'Просрочено', '', '')`)
+	assert.NoError(t, err)
+	overdue, err := res.LastInsertId()
+	assert.NoError(t, err)
+	defer func() { deleteTasks(t, []string{fmt.Sprint(overdue)}) }()
+
+	const year = "from=20920101&to=20921231"
+	tbl := []struct {
+		query string
+		want  []string
+	}{
+		{year, []string{ids[1], ids[0], ids[2], ids[3]}},
+		{"from=20920106&to=20920131", []string{ids[0], ids[2]}},
+		{year + "&recurring=true", []string{ids[1], ids[0], ids[3]}},
+		{year + "&recurring=false", []string{ids[2]}},
+		{year + "&repeat=d", []string{ids[0]}},
+		{year + "&repeat=y", []string{ids[3]}},
+		{year + "&has_comment=true", []string{ids[0], ids[2]}},
+		{year + "&has_comment=false&recurring=true", []string{ids[1], ids[3]}},
+		{year + "&sort=title", []string{ids[1], ids[0], ids[2], ids[3]}},
+		{year + "&sort=title&order=desc", []string{ids[3], ids[2], ids[0], ids[1]}},
+		{year + "&sort=created", ids},
+		{year + "&sort=date&order=desc", []string{ids[3], ids[2], ids[0], ids[1]}},
+		{"from=19920101&to=19921231&overdue=true", []string{fmt.Sprint(overdue)}},
+		{year + "&overdue=true", []string{}},
+	}
+	for _, v := range tbl {
+		assert.Equal(t, v.want, taskIDs(taskList(t, v.query)), v.query)
+	}
+
+	for _, query := range []string{
+		"from=2092-01-01", "to=завтра", "recurring=иногда", "repeat=x", "has_comment=2",
+		"overdue=да", "sort=rank", "order=up", "priority=7", "project=нет", "ready=может",
+	} {
+		assert.NotNil(t, taskList(t, query)["error"], query)
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTasks(t *testing.T, query string) (int, []map[string]string) {
+	body, err := requestJSON("api/tasks?search="+url.QueryEscape(query), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	if _, ok := m["error"]; ok {
+		return http.StatusBadRequest, nil
+	}
+
+	var tasks map[string][]map[string]string
+	err = json.Unmarshal(body, &tasks)
+	assert.NoError(t, err)
+	return http.StatusOK, tasks["tasks"]
+}
+
+func TestSearchQuery(t *testing.T) {
+	if !Search {
+		return
+	}
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	addTask(t, task{
+		date:    now.Format(`20060102`),
+		title:   "Годовой отчёт",
+		comment: "для налоговой",
+		repeat:  "d 3",
+	})
+	addTask(t, task{
+		date:    now.AddDate(0, 0, 5).Format(`20060102`),
+		title:   "Квартальный план",
+		comment: "Отчёт приложить",
+		repeat:  "w 1,3",
+	})
+	addTask(t, task{
+		date:  now.AddDate(0, 0, 10).Format(`20060102`),
+		title: "Созвон в 16:00",
+	})
+
+	tbl := []struct {
+		query string
+		want  int
+	}{
+		{"отчёт", 2},
+		{"ОТЧЁТ", 2},
+		{"title:отчёт", 1},
+		{"отчёт -план", 1},
+		{`"годовой отчёт"`, 1},
+		{`"отчёт годовой"`, 0},
+		{"repeat:w OR repeat:d", 2},
+		{"after:" + now.AddDate(0, 0, 1).Format(`02.01.2006`), 2},
+		{"before:" + now.AddDate(0, 0, 1).Format(`02.01.2006`) + " OR 16:00", 2},
+		{"-(title:годовой OR title:квартальный)", 1},
+	}
+	for _, v := range tbl {
+		code, tasks := searchTasks(t, v.query)
+		assert.Equal(t, http.StatusOK, code, v.query)
+		assert.Len(t, tasks, v.want, v.query)
+	}
+
+	for _, query := range []string{"after:", `"незакрытая`, "(план", "план OR", "before:32.01.2026"} {
+		code, _ := searchTasks(t, query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
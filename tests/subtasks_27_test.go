@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getSubtasks возвращает заголовки и отметки пунктов чек-листа по порядку
+func getSubtasks(t *testing.T, taskID string) (ids []int64, titles []string, done []bool) {
+	body, err := requestJSON("api/subtasks?task_id="+taskID, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m struct {
+		Subtasks []struct {
+			ID    int64  `json:"id"`
+			Title string `json:"title"`
+			Done  bool   `json:"done"`
+		} `json:"subtasks"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m), string(body))
+	for _, s := range m.Subtasks {
+		ids = append(ids, s.ID)
+		titles = append(titles, s.Title)
+		done = append(done, s.Done)
+	}
+	return ids, titles, done
+}
+
+func TestSubtasks(t *testing.T) {
+	id := addTask(t, task{date: "20960101", title: "Собраться в поход", repeat: "d 7"})
+	defer func() {
+		if id != "" {
+			deleteTasks(t, []string{id})
+		}
+	}()
+
+	// Правка чек-листа меняет версию задачи, чтобы её видели ETag и синхронизация CalDAV
+	version := getTask(t, id)["version"]
+	changed := func(action string) {
+		next := getTask(t, id)["version"]
+		assert.NotEqual(t, version, next, action)
+		version = next
+	}
+
+	for _, title := range []string{"Палатка", "Спальник", "Котелок"} {
+		ret, err := postJSON("api/subtasks", map[string]any{"task_id": id, "title": title}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["id"])
+		changed("add " + title)
+	}
+	for _, req := range []map[string]any{{"task_id": id, "title": "  "}, {"task_id": "999999", "title": "Ничей"}} {
+		ret, err := postJSON("api/subtasks", req, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["error"], req)
+	}
+
+	ids, titles, done := getSubtasks(t, id)
+	assert.Equal(t, []string{"Палатка", "Спальник", "Котелок"}, titles)
+	assert.Equal(t, []bool{false, false, false}, done)
+
+	ret, err := postJSON(fmt.Sprint("api/subtasks/toggle?id=", ids[1]), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret["done"])
+	changed("toggle")
+	assert.Equal(t, map[string]any{"done": 1.0, "total": 3.0}, getTask(t, id)["progress"])
+
+	// Порядок должен перечислять каждый пункт ровно один раз
+	for _, order := range [][]int64{{ids[2], ids[0]}, {ids[2], ids[0], ids[0]}, {ids[2], ids[0], 999999}} {
+		ret, err = postJSON("api/subtasks/reorder", map[string]any{"task_id": id, "order": order}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["error"], order)
+	}
+	ret, err = postJSON("api/subtasks/reorder", map[string]any{"task_id": id, "order": []int64{ids[2], ids[0], ids[1]}}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+	changed("reorder")
+	_, titles, done = getSubtasks(t, id)
+	assert.Equal(t, []string{"Котелок", "Палатка", "Спальник"}, titles)
+	assert.Equal(t, []bool{false, false, true}, done)
+
+	// Выполнение повторяющейся задачи начинает чек-лист заново
+	status, err := requestStatus("api/task/done?id="+id, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	_, _, done = getSubtasks(t, id)
+	assert.Equal(t, []bool{false, false, false}, done)
+
+	version = getTask(t, id)["version"]
+	status, err = requestStatus(fmt.Sprint("api/subtasks?id=", ids[0]), http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	changed("delete")
+	status, err = requestStatus(fmt.Sprint("api/subtasks?id=", ids[0]), http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, err = requestStatus(fmt.Sprint("api/subtasks/toggle?id=", ids[0]), http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// С задачей удаляется и её чек-лист
+	deleteTasks(t, []string{id})
+	id = ""
+	db := openDB(t)
+	defer db.Close()
+	var left int
+	assert.NoError(t, db.Get(&left, fmt.Sprintf("SELECT count(*) FROM subtasks WHERE id IN (%d, %d)", ids[1], ids[2])))
+	assert.Equal(t, 0, left)
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addTaggedTask(t *testing.T, title string, tags ...string) string {
+	ret, err := postJSON("api/task", map[string]any{
+		"date":  "20930101",
+		"title": title,
+		"tags":  tags,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	assert.NotEmpty(t, id)
+	return id
+}
+
+func TestTagVersions(t *testing.T) {
+	ids := []string{
+		addTaggedTask(t, "Помеченная", "метка-до", "другая-метка"),
+		addTaggedTask(t, "Сливаемая", "метка-слить"),
+		addTaggedTask(t, "Без меток"),
+	}
+	defer func() { deleteTasks(t, ids) }()
+
+	versions := taskVersions(t, ids)
+
+	ret, err := postJSON("api/tags/rename", map[string]any{"from": "метка-до", "to": "метка-после"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+
+	// Переименование метки меняет задачу, поэтому её версия и ETag тоже меняются
+	task := getTask(t, ids[0])
+	assert.ElementsMatch(t, []any{"метка-после", "другая-метка"}, task["tags"])
+	assert.NotEqual(t, versions[0], task["version"])
+	versions[0] = task["version"]
+	assert.Equal(t, versions[1], getTask(t, ids[1])["version"])
+
+	ret, err = postJSON("api/tags/merge", map[string]any{"from": []string{"метка-слить"}, "to": "метка-после"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, ret["error"])
+
+	task = getTask(t, ids[1])
+	assert.Equal(t, []any{"метка-после"}, task["tags"])
+	assert.NotEqual(t, versions[1], task["version"])
+	// У первой задачи метка уже была, набор меток не изменился
+	assert.Equal(t, versions[0], getTask(t, ids[0])["version"])
+	assert.Equal(t, versions[2], getTask(t, ids[2])["version"])
+
+	ret, err = postJSON("api/tags/rename", map[string]any{"from": "нет-такой-метки", "to": "всё-равно"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+	ret, err = postJSON("api/tags/rename", map[string]any{"from": "метка-после", "to": "другая-метка"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["error"])
+}
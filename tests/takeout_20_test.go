@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deeramster/go_final_project/googletasks"
+	"github.com/deeramster/go_final_project/todoist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoistParseDate(t *testing.T) {
+	// 9 января 2030 года — среда
+	now := time.Date(2030, 1, 9, 15, 0, 0, 0, time.Local)
+	tbl := []struct {
+		text   string
+		date   string
+		repeat string
+		lost   int
+	}{
+		{"", "20300109", "", 0},
+		{"tomorrow", "20300110", "", 0},
+		{"Jan 15 2030", "20300115", "", 0},
+		{"2030-02-01", "20300201", "", 0},
+		{"friday", "20300111", "", 0},
+		{"tomorrow at 10:00", "20300110", "", 1},
+		{"every day", "20300109", "d 1", 0},
+		{"every 3 days", "20300109", "d 3", 0},
+		{"every other week", "20300109", "d 14", 0},
+		{"every weekday", "20300109", "w 1,2,3,4,5", 0},
+		{"every mon, fri at 9am", "20300111", "w 1,5", 1},
+		{"every 15th", "20300115", "m 15", 0},
+		{"every last day", "20300131", "m -1", 0},
+		{"every 3 months", "20300109", "m 9 1,4,7,10", 0},
+		{"every jan 15", "20300115", "y", 0},
+		{"every! 2 days", "20300109", "d 2", 1},
+		{"every day until feb 1", "20300109", "d 1", 1},
+		{"every 2nd monday", "20300109", "", 1},
+		{"every hour", "20300109", "", 1},
+		{"когда-нибудь", "20300109", "", 1},
+	}
+	for _, v := range tbl {
+		date, repeat, lost := todoist.ParseDate(v.text, now)
+		assert.Equal(t, v.date, date, v.text)
+		assert.Equal(t, v.repeat, repeat, v.text)
+		assert.Len(t, lost, v.lost, v.text)
+	}
+}
+
+func TestTodoistRead(t *testing.T) {
+	now := time.Date(2030, 1, 9, 15, 0, 0, 0, time.Local)
+	input := "\ufeffTYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\n" +
+		"section,Дом,,,,,,,,\n" +
+		"task,Полить цветы @дача,,1,1,Я,,every 3 days,en,Europe/Moscow\n" +
+		"note,Фикус не поливать,,,,,,,,\n" +
+		"task,Купить лейку,,4,2,Я,,,en,Europe/Moscow\n" +
+		"task,Отчёт,,5,1,Я,,,en,Europe/Moscow\n" +
+		"task,Планёрка,,4,1,Я,Коллега,every 2nd monday,en,Europe/Moscow\n"
+
+	doc, lines, errs, warnings, err := todoist.Read(strings.NewReader(input), "Личное", now)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 7}, lines)
+	if assert.Len(t, doc.Tasks, 2) {
+		task := doc.Tasks[0]
+		assert.Equal(t, "Полить цветы", task.Title)
+		assert.Equal(t, "Фикус не поливать", task.Comment)
+		assert.Equal(t, "d 3", task.Repeat)
+		assert.Equal(t, 1, task.Priority)
+		assert.Equal(t, []string{"дача", "Дом"}, task.Tags)
+		if assert.Len(t, task.Subtasks, 1) {
+			assert.Equal(t, "Купить лейку", task.Subtasks[0].Title)
+		}
+		assert.Equal(t, 0, doc.Tasks[1].Priority)
+		assert.Empty(t, doc.Tasks[1].Repeat)
+	}
+	if assert.Len(t, doc.Projects, 1) {
+		assert.Equal(t, "Личное", doc.Projects[0].Name)
+	}
+	// Приоритет 5 в Todoist не бывает
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, 6, errs[0].Line)
+	}
+	// Правило повторения и исполнитель планёрки потеряны
+	assert.Len(t, warnings, 2)
+
+	_, _, _, _, err = todoist.Read(strings.NewReader("title,date\nЗадача,20300101\n"), "", now)
+	assert.ErrorIs(t, err, todoist.ErrNotTodoist)
+}
+
+const takeoutJSON = `{
+  "kind": "tasks#taskLists",
+  "items": [{
+    "kind": "tasks#taskList",
+    "title": "Мои задачи",
+    "items": [
+      {"id": "c", "title": "Купить хлеб", "parent": "a", "status": "needsAction"},
+      {"id": "a", "title": "Магазин", "notes": "до закрытия", "status": "needsAction", "due": "2030-01-07T00:00:00.000Z"},
+      {"id": "b", "title": "Старое", "status": "completed", "completed": "2029-12-01T10:00:00.000Z"},
+      {"id": "e", "title": "Удалённое", "status": "needsAction", "deleted": true},
+      {"id": "d", "title": "Статья", "status": "needsAction", "links": [{"type": "email", "link": "https://mail.example.com"}]}
+    ]
+  }]
+}`
+
+func TestGoogleTasksRead(t *testing.T) {
+	doc, errs, warnings, err := googletasks.Read(strings.NewReader(takeoutJSON))
+	assert.NoError(t, err)
+	if assert.Len(t, doc.Projects, 1) {
+		assert.Equal(t, "Мои задачи", doc.Projects[0].Name)
+	}
+	if assert.Len(t, doc.Tasks, 2) {
+		task := doc.Tasks[0]
+		assert.Equal(t, "Магазин", task.Title)
+		assert.Equal(t, "до закрытия", task.Comment)
+		assert.Equal(t, "20300107", task.Date)
+		if assert.Len(t, task.Subtasks, 1) {
+			assert.Equal(t, "Купить хлеб", task.Subtasks[0].Title)
+		}
+		// Задача без срока получает сегодняшнюю дату
+		assert.Equal(t, time.Now().Format(`20060102`), doc.Tasks[1].Date)
+	}
+	assert.Empty(t, errs)
+	// Выполненная и удалённая задачи пропущены, у статьи потеряна ссылка
+	if assert.Len(t, warnings, 3) {
+		assert.Equal(t, "Старое", warnings[0].Title)
+		assert.Equal(t, "completed task skipped", warnings[0].Message)
+		assert.Equal(t, "Удалённое", warnings[1].Title)
+		assert.Equal(t, "deleted task skipped", warnings[1].Message)
+		assert.Equal(t, "Статья", warnings[2].Title)
+	}
+
+	_, _, _, err = googletasks.Read(strings.NewReader(`{"kind": "calendar#events"}`))
+	assert.ErrorIs(t, err, googletasks.ErrNotTakeout)
+}
+
+func TestImportTakeout(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	// Выполненные и удалённые задачи не мешают импорту остальных
+	body, err := requestBody("api/import/google?dry_run=true", takeoutJSON)
+	assert.NoError(t, err)
+	assert.Contains(t, body, `"created":2`)
+	assert.Contains(t, body, `"preview"`)
+	assert.Contains(t, body, `completed task skipped`)
+	assert.Contains(t, body, `links dropped`)
+	assert.NotContains(t, body, `"errors"`)
+
+	input := "TYPE,CONTENT,PRIORITY,INDENT,DATE\ntask,Из Todoist,4,1,every 2nd monday\n"
+	body, err = requestBody("api/import/todoist?dry_run=true&project=Входящие", input)
+	assert.NoError(t, err)
+	assert.Contains(t, body, `"created":1`)
+	assert.Contains(t, body, `Из Todoist`)
+	assert.Contains(t, body, `can't be represented`)
+
+	body, err = requestBody("api/import/todoist?dry_run=true", "просто текст")
+	assert.NoError(t, err)
+	assert.Contains(t, body, `Invalid Todoist export`)
+
+	after, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/deeramster/go_final_project/models"
+	"github.com/deeramster/go_final_project/todotxt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	home := int64(1)
+	doc := models.Export{
+		Projects: []models.ExportProject{{ID: home, Name: "Мой дом"}},
+		Tasks: []models.ExportTask{
+			// 20300107 — понедельник, 20300215 — 15-е число
+			{ID: "1", Date: "20300107", Title: "Вынести мусор", Repeat: "w 1", Priority: 1, ProjectID: &home, Tags: []string{"дом", "вечер"}},
+			{ID: "2", Date: "20300215", Title: "Оплатить счета", Repeat: "m 15", Priority: 4},
+			{ID: "3", Date: "20300105", Title: "Полить цветы", Repeat: "d 3"},
+			{ID: "4", Date: "20300101", Title: "Годовщина", Repeat: "y"},
+			{ID: "5", Date: "20300102", Title: "Спортзал", Repeat: "w 3,5"},
+			{ID: "6", Date: "20300131", Title: "Отчёт", Repeat: "m -1"},
+			{ID: "7", Date: "20300110", Title: "Позвонить в 16:00"},
+			// Заголовки, похожие на разметку todo.txt
+			{ID: "8", Date: "20300111", Title: "x Сделать рентген"},
+			{ID: "9", Date: "20300112", Title: "(A) класс"},
+			{ID: "10", Date: "20300113", Title: "2030-01-01 начало года"},
+			{ID: "11", Date: "20300114", Title: "Поставить +1 и написать @ivan", Priority: 2},
+			{ID: "12", Date: "20300115", Title: "Перенести due:завтра rec:1d repeat:нет"},
+			{ID: "13", Date: "20300116", Title: `\server\share и 2030-01-01`, Priority: 3},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := todotxt.Write(&buf, doc)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "(A) Вынести мусор +Мой_дом @дом @вечер due:2030-01-07 rec:1w\n")
+	assert.Contains(t, buf.String(), "Спортзал due:2030-01-02 repeat:w_3,5\n")
+	assert.Contains(t, buf.String(), "\\x Сделать рентген due:2030-01-11\n")
+	assert.Contains(t, buf.String(), "(B) Поставить \\+1 и написать \\@ivan due:2030-01-14\n")
+
+	got, lines, errs, warnings, err := todotxt.Read(&buf)
+	assert.NoError(t, err)
+	assert.Empty(t, errs)
+	assert.Empty(t, warnings)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}, lines)
+	if !assert.Len(t, got.Tasks, len(doc.Tasks)) {
+		return
+	}
+	for i, want := range doc.Tasks {
+		task := got.Tasks[i]
+		assert.Equal(t, want.Title, task.Title)
+		assert.Equal(t, want.Date, task.Date)
+		assert.Equal(t, want.Repeat, task.Repeat)
+		assert.Equal(t, want.Priority, task.Priority)
+		assert.Equal(t, want.Tags, task.Tags)
+		assert.Equal(t, i == 0, task.ProjectID != nil, want.Title)
+	}
+	if assert.NotNil(t, got.Tasks[0].ProjectID) && assert.Len(t, got.Projects, 1) {
+		assert.Equal(t, "Мой дом", got.Projects[0].Name)
+	}
+}
+
+func TestTodoTxtRead(t *testing.T) {
+	input := "(B) 2029-12-01 Купить молоко @магазин +покупки +еда due:2030-01-02\n" +
+		"x 2029-12-02 Сделанная задача\n" +
+		"\n" +
+		"(F) Низкий приоритет due:2030-01-03 rec:2m\n" +
+		"Раз в две недели due:2030-01-04 rec:+2w\n" +
+		"Плохая дата due:2030-02-31\n"
+
+	doc, lines, errs, warnings, err := todotxt.Read(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 4, 5}, lines)
+	if assert.Len(t, doc.Tasks, 3) {
+		task := doc.Tasks[0]
+		assert.Equal(t, "Купить молоко", task.Title)
+		assert.Equal(t, 2, task.Priority)
+		assert.Equal(t, "20300102", task.Date)
+		assert.Equal(t, []string{"магазин", "еда"}, task.Tags)
+		if assert.NotNil(t, task.ProjectID) && assert.Len(t, doc.Projects, 1) {
+			assert.Equal(t, "покупки", doc.Projects[0].Name)
+		}
+
+		assert.Equal(t, 4, doc.Tasks[1].Priority)
+		assert.Empty(t, doc.Tasks[1].Repeat)
+		assert.Equal(t, "d 14", doc.Tasks[2].Repeat)
+	}
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, 6, errs[0].Line)
+	}
+	// Выполненная задача пропущена, приоритет (F) и rec:2m записаны с потерями
+	if assert.Len(t, warnings, 3) {
+		assert.Equal(t, 2, warnings[0].Line)
+		assert.Equal(t, 4, warnings[1].Line)
+		assert.Equal(t, 4, warnings[2].Line)
+	}
+}
+
+func TestImportTodoTxt(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	body, err := requestBody("api/import/todotxt?dry_run=true", "(A) Из todo.txt due:2030-01-02 rec:3m\n")
+	assert.NoError(t, err)
+	assert.Contains(t, body, `"created":1`)
+	assert.Contains(t, body, `"warnings"`)
+	assert.Contains(t, body, `"row":1`)
+
+	// Выполненная задача не мешает импорту остальных
+	body, err = requestBody("api/import/todotxt?dry_run=true", "x 2029-12-01 Сделано\nОсталось due:2030-01-02\n")
+	assert.NoError(t, err)
+	assert.Contains(t, body, `"created":1`)
+	assert.Contains(t, body, "completed task skipped")
+	assert.NotContains(t, body, `"errors"`)
+
+	after, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+}
@@ -0,0 +1,420 @@
+// Package todoist reads the CSV file Todoist exports for a project.
+//
+// Rows of type "task" become tasks: CONTENT is the title with @labels as
+// tags, DESCRIPTION and following "note" rows the comment, and DATE the date
+// and repeat rule. Indented tasks become checklist items of the task above,
+// sections become tags. Todoist priority p1 is our 1, p4 means no priority.
+package todoist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/deeramster/go_final_project/models"
+)
+
+// Issue describes a row that could not be read or was read with losses
+type Issue struct {
+	Line    int
+	Message string
+}
+
+// ErrNotTodoist is returned for CSV without the TYPE and CONTENT columns of a Todoist export
+var ErrNotTodoist = errors.New("not a Todoist export: TYPE and CONTENT columns are required")
+
+// maxDays is the longest interval of the "d" rule
+const maxDays = 400
+
+var bom = []byte("\ufeff")
+
+var (
+	labelPattern = regexp.MustCompile(`(^|\s)@(\S+)`)
+	// timePattern finds a time of day such as "at 10:30", "9am" or "18:00"
+	timePattern     = regexp.MustCompile(`(\s+at)?\s+\d{1,2}(:\d{2})?\s*(am|pm)|(\s+at)?\s+\d{1,2}:\d{2}`)
+	intervalPattern = regexp.MustCompile(`^(\d+) (day|week|month)s?$`)
+	ordinalPattern  = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)?$`)
+)
+
+var weekdayNames = map[string]int{
+	"mon": 1, "monday": 1, "tue": 2, "tues": 2, "tuesday": 2, "wed": 3, "wednesday": 3,
+	"thu": 4, "thur": 4, "thurs": 4, "thursday": 4, "fri": 5, "friday": 5,
+	"sat": 6, "saturday": 6, "sun": 7, "sunday": 7,
+}
+
+var monthNames = map[string]time.Month{
+	"jan": 1, "january": 1, "feb": 2, "february": 2, "mar": 3, "march": 3, "apr": 4, "april": 4,
+	"may": 5, "jun": 6, "june": 6, "jul": 7, "july": 7, "aug": 8, "august": 8,
+	"sep": 9, "sept": 9, "september": 9, "oct": 10, "october": 10, "nov": 11, "november": 11, "dec": 12, "december": 12,
+}
+
+// dateLayouts are the fixed dates Todoist writes, after month names are capitalized
+var dateLayouts = []string{"2006-01-02", "2006/01/02", "02.01.2006", "Jan 2 2006", "Jan 2, 2006", "January 2 2006", "January 2, 2006", "2 Jan 2006", "2 January 2006"}
+
+// Read converts a Todoist CSV export to an export document ready for import;
+// project names the project all tasks go to, none when empty. Rows that can't
+// be read are reported in errs and left out, losses in warnings. lines is the
+// line of each returned task.
+func Read(r io.Reader, project string, now time.Time) (doc models.Export, lines []int, errs, warnings []Issue, err error) {
+	doc = models.Export{Format: models.ExportFormat, Version: models.ExportVersion, Projects: []models.ExportProject{}, Tasks: []models.ExportTask{}}
+
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(bom)); bytes.Equal(head, bom) {
+		br.Discard(len(bom))
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return doc, nil, nil, nil, ErrNotTodoist
+	}
+	if err != nil {
+		return doc, nil, nil, nil, err
+	}
+	columns := make(map[string]int)
+	for i, h := range header {
+		columns[strings.ToUpper(strings.TrimSpace(h))] = i
+	}
+	if _, ok := columns["TYPE"]; !ok {
+		return doc, nil, nil, nil, ErrNotTodoist
+	}
+	if _, ok := columns["CONTENT"]; !ok {
+		return doc, nil, nil, nil, ErrNotTodoist
+	}
+
+	var projectID *int64
+	if project = strings.TrimSpace(project); project != "" {
+		id := int64(1)
+		projectID = &id
+		doc.Projects = append(doc.Projects, models.ExportProject{ID: id, Name: project})
+	}
+
+	// parent is the index of the last top-level task, which takes the notes and subtasks below it
+	parent := -1
+	var section string
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return doc, nil, nil, nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		value := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		warn := func(msg string) {
+			warnings = append(warnings, Issue{Line: line, Message: msg})
+		}
+
+		switch strings.ToLower(value("TYPE")) {
+		case "section":
+			section = value("CONTENT")
+			parent = -1
+			continue
+		case "note":
+			if parent < 0 {
+				warn("note without a task dropped")
+			} else if note := value("CONTENT"); note != "" {
+				t := &doc.Tasks[parent]
+				t.Comment = strings.TrimSpace(t.Comment + "\n\n" + note)
+			}
+			continue
+		case "task":
+		default:
+			// Empty separator rows and the meta rows of newer exports
+			continue
+		}
+
+		title, tags := splitLabels(value("CONTENT"))
+		if indent, _ := strconv.Atoi(value("INDENT")); indent > 1 {
+			if parent < 0 {
+				errs = append(errs, Issue{Line: line, Message: "subtask without a parent task"})
+				continue
+			}
+			doc.Tasks[parent].Subtasks = append(doc.Tasks[parent].Subtasks, models.ExportSubtask{Title: title})
+			if value("DATE") != "" || value("DESCRIPTION") != "" || len(tags) > 0 {
+				warn("date, description and labels of a subtask dropped")
+			}
+			continue
+		}
+
+		t := models.ExportTask{
+			ID:        strconv.Itoa(line),
+			Title:     title,
+			Comment:   value("DESCRIPTION"),
+			Tags:      tags,
+			ProjectID: projectID,
+		}
+		if section != "" {
+			t.Tags = append(t.Tags, section)
+		}
+		if s := value("PRIORITY"); s != "" {
+			p, err := strconv.Atoi(s)
+			if err != nil || p < 1 || p > 4 {
+				errs = append(errs, Issue{Line: line, Message: fmt.Sprintf("invalid priority %q", s)})
+				continue
+			}
+			if p < 4 {
+				t.Priority = p
+			}
+		}
+
+		var lost []string
+		t.Date, t.Repeat, lost = ParseDate(value("DATE"), now)
+		for _, msg := range lost {
+			warn(msg)
+		}
+		for _, field := range []struct{ column, name string }{
+			{"RESPONSIBLE", "assignee"}, {"DURATION", "duration"}, {"DEADLINE", "deadline"},
+		} {
+			if value(field.column) != "" {
+				warn(field.name + " dropped")
+			}
+		}
+
+		parent = len(doc.Tasks)
+		doc.Tasks = append(doc.Tasks, t)
+		lines = append(lines, line)
+	}
+	return doc, lines, errs, warnings, nil
+}
+
+// splitLabels takes the @labels out of a title
+func splitLabels(content string) (title string, labels []string) {
+	for _, m := range labelPattern.FindAllStringSubmatch(content, -1) {
+		labels = append(labels, m[2])
+	}
+	title = labelPattern.ReplaceAllString(content, "$1")
+	return strings.Join(strings.Fields(title), " "), labels
+}
+
+// ParseDate reads the DATE text of a Todoist task, such as "tomorrow",
+// "Jan 15 2030" or "every monday, friday at 9am", as a date and a repeat
+// rule. lost lists what had to be dropped: a time of day, the end of a
+// recurrence or a recurrence or date that can't be represented, in which
+// case the task is due on today.
+func ParseDate(text string, now time.Time) (date, repeat string, lost []string) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	date = today.Format(dateutil.DateLayout)
+	s := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	if s == "" || s == "no date" {
+		return date, "", nil
+	}
+
+	if stripped := timePattern.ReplaceAllString(s, ""); stripped != s {
+		lost = append(lost, fmt.Sprintf("time of day dropped from %q", text))
+		s = strings.TrimSpace(stripped)
+	}
+
+	every, recurring := strings.CutPrefix(s, "every")
+	switch s {
+	case "daily":
+		every, recurring = " day", true
+	case "weekly":
+		every, recurring = " week", true
+	case "monthly":
+		every, recurring = " month", true
+	case "yearly", "annually":
+		every, recurring = " year", true
+	}
+	if !recurring {
+		d, ok := fixedDate(s, today)
+		if !ok {
+			return date, "", append(lost, fmt.Sprintf("date %q not recognized, the task is due today", text))
+		}
+		return d.Format(dateutil.DateLayout), "", lost
+	}
+
+	if rest, ok := strings.CutPrefix(every, "!"); ok {
+		every = rest
+		lost = append(lost, "repeat counted from completion is counted from the due date")
+	}
+	for _, word := range []string{" until ", " ending ", " for ", " starting ", " from "} {
+		if i := strings.Index(every, word); i >= 0 {
+			lost = append(lost, fmt.Sprintf("%q dropped from recurrence", strings.TrimSpace(every[i:])))
+			every = every[:i]
+		}
+	}
+
+	repeat, start, ok := repeatRule(strings.TrimSpace(every), today)
+	if !ok {
+		return date, "", append(lost, fmt.Sprintf("recurrence %q can't be represented and was dropped", text))
+	}
+	return start.Format(dateutil.DateLayout), repeat, lost
+}
+
+// repeatRule translates the text after "every" to a repeat rule and its first date
+func repeatRule(every string, today time.Time) (repeat string, start time.Time, ok bool) {
+	every = strings.TrimPrefix(every, "1 ")
+	every = strings.Replace(every, "other ", "2 ", 1)
+
+	switch every {
+	case "day":
+		return "d 1", today, true
+	case "week":
+		return "w " + strconv.Itoa(isoWeekday(today)), today, true
+	case "weekday", "workday":
+		return firstOf("w 1,2,3,4,5", today)
+	case "weekend":
+		return firstOf("w 6,7", today)
+	case "month":
+		return "m " + strconv.Itoa(today.Day()), today, true
+	case "last day", "last day of the month", "last day of month":
+		return firstOf("m -1", today)
+	case "year":
+		return "y", today, true
+	}
+
+	if m := intervalPattern.FindStringSubmatch(every); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch {
+		case m[2] == "day" && n <= maxDays:
+			return "d " + m[1], today, true
+		case m[2] == "week" && n*7 <= maxDays:
+			return "d " + strconv.Itoa(n*7), today, true
+		case m[2] == "month" && n <= 12 && 12%n == 0:
+			// Every few months is the same months of every year
+			var months []int
+			for k := 0; k < 12/n; k++ {
+				months = append(months, (int(today.Month())-1+k*n)%12+1)
+			}
+			slices.Sort(months)
+			return "m " + strconv.Itoa(today.Day()) + " " + joinNumbers(months), today, true
+		}
+		return "", today, false
+	}
+
+	// A list of weekdays or of days of the month: "mon, fri", "1st and 15th"
+	items := strings.FieldsFunc(strings.ReplaceAll(every, " and ", ","), func(r rune) bool { return r == ',' })
+	var weekdays, monthDays []int
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if n, ok := weekdayNames[item]; ok {
+			weekdays = append(weekdays, n)
+			continue
+		}
+		if m := ordinalPattern.FindStringSubmatch(item); m != nil {
+			if n, _ := strconv.Atoi(m[1]); n >= 1 && n <= 31 {
+				monthDays = append(monthDays, n)
+				continue
+			}
+		}
+		weekdays, monthDays = nil, nil
+		break
+	}
+	switch {
+	case len(weekdays) > 0 && len(monthDays) == 0:
+		slices.Sort(weekdays)
+		return firstOf("w "+joinNumbers(slices.Compact(weekdays)), today)
+	case len(monthDays) > 0 && len(weekdays) == 0:
+		slices.Sort(monthDays)
+		return firstOf("m "+joinNumbers(slices.Compact(monthDays)), today)
+	}
+
+	// A day of the year: "jan 15", "15 january"
+	if d, ok := dayOfYear(every, today); ok {
+		return "y", d, true
+	}
+	return "", today, false
+}
+
+// firstOf returns a rule with its first date on or after today
+func firstOf(repeat string, today time.Time) (string, time.Time, bool) {
+	yesterday := today.AddDate(0, 0, -1)
+	next, err := dateutil.NextDate(yesterday, yesterday.Format(dateutil.DateLayout), repeat)
+	if err != nil {
+		return "", today, false
+	}
+	start, err := time.Parse(dateutil.DateLayout, next)
+	if err != nil {
+		return "", today, false
+	}
+	return repeat, start, true
+}
+
+// fixedDate reads a date that doesn't repeat
+func fixedDate(s string, today time.Time) (time.Time, bool) {
+	switch s {
+	case "today", "tod":
+		return today, true
+	case "tomorrow", "tom":
+		return today.AddDate(0, 0, 1), true
+	}
+	if n, ok := weekdayNames[strings.TrimPrefix(s, "next ")]; ok {
+		// The nearest such weekday, today included
+		return today.AddDate(0, 0, (n-isoWeekday(today)+7)%7), true
+	}
+
+	words := strings.Fields(s)
+	for i, w := range words {
+		if len(w) > 0 && w[0] >= 'a' && w[0] <= 'z' {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	capitalized := strings.Join(words, " ")
+	for _, layout := range dateLayouts {
+		if d, err := time.Parse(layout, capitalized); err == nil {
+			return d, true
+		}
+	}
+	return dayOfYear(s, today)
+}
+
+// dayOfYear reads a month and day without a year, such as "jan 15" or "15 january",
+// as the nearest such date on or after today
+func dayOfYear(s string, today time.Time) (time.Time, bool) {
+	words := strings.Fields(strings.ReplaceAll(s, ",", ""))
+	if len(words) != 2 {
+		return today, false
+	}
+	month, ok := monthNames[words[0]]
+	dayWord := words[1]
+	if !ok {
+		month, ok = monthNames[words[1]]
+		dayWord = words[0]
+	}
+	m := ordinalPattern.FindStringSubmatch(dayWord)
+	if !ok || m == nil {
+		return today, false
+	}
+	day, _ := strconv.Atoi(m[1])
+	d := time.Date(today.Year(), month, day, 0, 0, 0, 0, time.UTC)
+	if d.Month() != month {
+		return today, false
+	}
+	if d.Before(today) {
+		d = d.AddDate(1, 0, 0)
+	}
+	return d, true
+}
+
+func joinNumbers(numbers []int) string {
+	list := make([]string, len(numbers))
+	for i, n := range numbers {
+		list[i] = strconv.Itoa(n)
+	}
+	return strings.Join(list, ",")
+}
+
+// isoWeekday numbers weekdays from 1 for Monday to 7 for Sunday, as the "w" rule does
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
@@ -0,0 +1,310 @@
+// Package todotxt converts tasks to and from todo.txt lines.
+//
+// A task becomes one line: "(A) Title +project @tag due:2030-01-02 rec:1w".
+// Priorities 1-4 are (A)-(D), the date is the due: extension and the repeat
+// rule is the rec: extension when todo.txt can express it, otherwise our own
+// repeat: extension with spaces written as underscores. Title words that
+// would read as syntax, like "+1" or a leading "x", are escaped with a backslash.
+package todotxt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deeramster/go_final_project/dateutil"
+	"github.com/deeramster/go_final_project/models"
+)
+
+// dueLayout is the date format of todo.txt
+const dueLayout = "2006-01-02"
+
+// maxDays is the longest interval of the "d" rule
+const maxDays = 400
+
+var (
+	priorityPattern = regexp.MustCompile(`^\(([A-Z])\)$`)
+	recPattern      = regexp.MustCompile(`^\+?([1-9][0-9]*)([dwmy])$`)
+)
+
+// ErrCompleted is returned by Parse for a completed "x ..." line
+var ErrCompleted = errors.New("completed task skipped")
+
+// Issue describes a line that could not be read or was read with losses
+type Issue struct {
+	Line    int
+	Message string
+}
+
+// Write writes every task of doc as a todo.txt line
+func Write(w io.Writer, doc models.Export) error {
+	projects := make(map[int64]string)
+	for _, p := range doc.Projects {
+		projects[p.ID] = p.Name
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, t := range doc.Tasks {
+		var project string
+		if t.ProjectID != nil {
+			project = projects[*t.ProjectID]
+		}
+		if _, err := bw.WriteString(Format(t, project) + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Format returns the todo.txt line of a task in the named project.
+// Comments and checklists have no place in todo.txt and are left out.
+func Format(t models.ExportTask, project string) string {
+	var parts []string
+	if t.Priority >= 1 && t.Priority <= 4 {
+		parts = append(parts, "("+string(rune('A'+t.Priority-1))+")")
+	}
+	parts = append(parts, escapeTitle(t.Title))
+	if project != "" {
+		parts = append(parts, "+"+word(project))
+	}
+	for _, tag := range t.Tags {
+		parts = append(parts, "@"+word(tag))
+	}
+
+	date, err := time.Parse(dateutil.DateLayout, t.Date)
+	if err == nil {
+		parts = append(parts, "due:"+date.Format(dueLayout))
+	}
+	if t.Repeat != "" {
+		if rec, ok := recurrence(t.Repeat, date); ok && err == nil {
+			parts = append(parts, "rec:"+rec)
+		} else {
+			parts = append(parts, "repeat:"+word(t.Repeat))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// recurrence translates a repeat rule to a rec: value when one means the same
+func recurrence(repeat string, date time.Time) (string, bool) {
+	fields := strings.Fields(repeat)
+	switch {
+	case repeat == "y":
+		return "1y", true
+	case len(fields) == 2 && fields[0] == "d":
+		return fields[1] + "d", true
+	case len(fields) == 2 && fields[0] == "w":
+		// Weekly on the due weekday
+		if day, err := strconv.Atoi(fields[1]); err == nil && day == isoWeekday(date) {
+			return "1w", true
+		}
+	case len(fields) == 2 && fields[0] == "m":
+		// Monthly on the due day
+		if day, err := strconv.Atoi(fields[1]); err == nil && day == date.Day() {
+			return "1m", true
+		}
+	}
+	return "", false
+}
+
+// escapeTitle prefixes the title words Parse would take for todo.txt syntax
+// with a backslash: a leading "x", priority or date, +projects, @contexts and
+// the extensions Parse reads. Words already starting with a backslash get one
+// more, as Parse strips one from every word.
+func escapeTitle(title string) string {
+	words := strings.Fields(title)
+	for i, w := range words {
+		key, _, isExt := strings.Cut(w, ":")
+		escape := strings.HasPrefix(w, "\\") ||
+			len(w) > 1 && (w[0] == '+' || w[0] == '@') ||
+			isExt && (key == "due" || key == "rec" || key == "repeat")
+		if i == 0 {
+			_, err := time.Parse(dueLayout, w)
+			escape = escape || w == "x" || priorityPattern.MatchString(w) || err == nil
+		}
+		if escape {
+			words[i] = "\\" + w
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Read converts todo.txt lines to an export document ready for import.
+// Completed lines are skipped with a warning, unreadable ones are reported
+// in errs and left out, losses go to warnings. lines is the line number of
+// each returned task.
+func Read(r io.Reader) (doc models.Export, lines []int, errs, warnings []Issue, err error) {
+	doc = models.Export{Format: models.ExportFormat, Version: models.ExportVersion, Projects: []models.ExportProject{}, Tasks: []models.ExportTask{}}
+	projects := make(map[string]int64)
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		t, project, lost, err := Parse(line)
+		if errors.Is(err, ErrCompleted) {
+			warnings = append(warnings, Issue{Line: n, Message: err.Error()})
+			continue
+		}
+		if err != nil {
+			errs = append(errs, Issue{Line: n, Message: err.Error()})
+			continue
+		}
+		for _, msg := range lost {
+			warnings = append(warnings, Issue{Line: n, Message: msg})
+		}
+
+		if project != "" {
+			id, ok := projects[project]
+			if !ok {
+				id = int64(len(projects) + 1)
+				projects[project] = id
+				doc.Projects = append(doc.Projects, models.ExportProject{ID: id, Name: project})
+			}
+			t.ProjectID = &id
+		}
+		t.ID = strconv.Itoa(n)
+		doc.Tasks = append(doc.Tasks, t)
+		lines = append(lines, n)
+	}
+	return doc, lines, errs, warnings, sc.Err()
+}
+
+// Parse reads one todo.txt line. The first +project becomes the project,
+// @contexts and further +projects become tags. A task without due: is
+// due today. A backslash before a word keeps it in the title as is.
+// Completed tasks return ErrCompleted since done tasks aren't stored.
+func Parse(line string) (t models.ExportTask, project string, warnings []string, err error) {
+	words := strings.Fields(line)
+	if len(words) > 0 && words[0] == "x" {
+		return t, "", nil, ErrCompleted
+	}
+
+	if len(words) > 0 {
+		if m := priorityPattern.FindStringSubmatch(words[0]); m != nil {
+			t.Priority = int(m[1][0]-'A') + 1
+			if t.Priority > 4 {
+				warnings = append(warnings, fmt.Sprintf("priority (%s) lowered to (D)", m[1]))
+				t.Priority = 4
+			}
+			words = words[1:]
+		}
+	}
+	// The creation date has no counterpart in a task
+	if len(words) > 0 {
+		if _, err := time.Parse(dueLayout, words[0]); err == nil {
+			words = words[1:]
+		}
+	}
+
+	var title []string
+	var due time.Time
+	var rec, repeat string
+	for _, w := range words {
+		key, value, isExt := strings.Cut(w, ":")
+		switch {
+		case strings.HasPrefix(w, "\\"):
+			title = append(title, w[1:])
+		case len(w) > 1 && w[0] == '+':
+			if project == "" {
+				project = unword(w[1:])
+			} else {
+				t.Tags = append(t.Tags, unword(w[1:]))
+			}
+		case len(w) > 1 && w[0] == '@':
+			t.Tags = append(t.Tags, unword(w[1:]))
+		case isExt && key == "due":
+			due, err = time.Parse(dueLayout, value)
+			if err != nil {
+				return t, "", nil, fmt.Errorf("invalid due date %q", value)
+			}
+		case isExt && key == "rec":
+			rec = value
+		case isExt && key == "repeat":
+			repeat = unword(value)
+		default:
+			title = append(title, w)
+		}
+	}
+	t.Title = strings.Join(title, " ")
+
+	if due.IsZero() {
+		due = time.Now()
+	}
+	t.Date = due.Format(dateutil.DateLayout)
+
+	switch {
+	case repeat != "":
+		t.Repeat = repeat
+	case rec != "":
+		var ok bool
+		if t.Repeat, ok = repeatRule(rec, due); !ok {
+			warnings = append(warnings, fmt.Sprintf("recurrence rec:%s can't be represented and was dropped", rec))
+		}
+	}
+	return t, project, warnings, nil
+}
+
+// repeatRule translates a rec: value to a repeat rule anchored at due
+func repeatRule(rec string, due time.Time) (string, bool) {
+	m := recPattern.FindStringSubmatch(rec)
+	if m == nil {
+		return "", false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return "", false
+	}
+
+	switch m[2] {
+	case "d":
+		if n <= maxDays {
+			return "d " + strconv.Itoa(n), true
+		}
+	case "w":
+		if n == 1 {
+			return "w " + strconv.Itoa(isoWeekday(due)), true
+		}
+		if n*7 <= maxDays {
+			return "d " + strconv.Itoa(n*7), true
+		}
+	case "m":
+		if n == 1 {
+			return "m " + strconv.Itoa(due.Day()), true
+		}
+	case "y":
+		if n == 1 {
+			return "y", true
+		}
+	}
+	return "", false
+}
+
+// isoWeekday numbers weekdays from 1 for Monday to 7 for Sunday, as the "w" rule does
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// word makes a name fit into one todo.txt word
+func word(s string) string {
+	return strings.Join(strings.Fields(s), "_")
+}
+
+// unword restores the spaces replaced by word
+func unword(s string) string {
+	return strings.ReplaceAll(s, "_", " ")
+}